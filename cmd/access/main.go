@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"personalwebsite/internal/access"
+	"personalwebsite/internal/config"
+	"personalwebsite/internal/portfolio"
+	"strings"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  access hash    read a password from stdin and print its album.yaml password_hash line")
+	fmt.Fprintln(os.Stderr, "  access share -album <path> [-ttl 72h] [-base <server URL>]")
+	fmt.Fprintln(os.Stderr, "                 print a link that unlocks a private album until it expires (needs SESSION_SECRET,")
+	fmt.Fprintln(os.Stderr, "                 and SERVER_URL unless -base is given)")
+	os.Exit(2)
+}
+
+func hashCommand() {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		fmt.Fprintf(os.Stderr, "Error reading password: %v\n", err)
+		os.Exit(1)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "Error: empty password")
+		os.Exit(1)
+	}
+
+	hash, err := access.HashPassword(password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error hashing password: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("password_hash: %q\n", hash)
+}
+
+func shareCommand(args []string) {
+	flags := flag.NewFlagSet("share", flag.ExitOnError)
+	album := flags.String("album", "", "private album path, e.g. Clients/Smith")
+	ttl := flags.Duration("ttl", 72*time.Hour, "how long the link stays valid")
+	base := flags.String("base", config.ServerURL(), "URL of the server the link points at; the static site has no private albums")
+	root := flags.String("root", config.ResolvePortfolioRoot(), "portfolio directory holding the album")
+	flags.Parse(args)
+
+	secret := os.Getenv("SESSION_SECRET")
+	if *album == "" || *ttl <= 0 {
+		usage()
+	}
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "Error: SESSION_SECRET must match the server's")
+		os.Exit(1)
+	}
+	if baseURL, err := url.Parse(*base); err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		fmt.Fprintln(os.Stderr, "Error: set -base or SERVER_URL to the server's URL, e.g. https://photos.example.com")
+		os.Exit(1)
+	}
+
+	// Links unlock the album holding the password, which may be a parent of the one shared.
+	albumAccess, err := portfolio.LoadAccess(*root, *album)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading album %s: %v\n", *album, err)
+		os.Exit(1)
+	}
+	if !albumAccess.IsPrivate() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not private; share its public URL instead\n", *album)
+		os.Exit(1)
+	}
+	if albumAccess.Album != *album {
+		fmt.Fprintf(os.Stderr, "Note: the link unlocks all of %s\n", albumAccess.Album)
+	}
+
+	expires := time.Now().Add(*ttl)
+	query := access.NewShareLinks([]byte(secret)).Sign(albumAccess.Album, expires)
+	link := url.URL{Path: "/portfolio/" + *album, RawQuery: query.Encode()}
+	fmt.Println(strings.TrimSuffix(*base, "/") + link.String())
+	fmt.Fprintf(os.Stderr, "Expires %s\n", expires.Format(time.RFC1123))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "hash":
+		hashCommand()
+	case "share":
+		shareCommand(os.Args[2:])
+	default:
+		usage()
+	}
+}
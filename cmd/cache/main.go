@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"personalwebsite/internal/config"
+	"personalwebsite/internal/images"
+	"time"
+)
+
+// megabytes formats a byte count for the report.
+func megabytes(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+}
+
+func main() {
+	maxMB := flag.Int64("max-mb", config.CacheMaxBytes()>>20, "budget to prune to, in megabytes (0 for none)")
+	prune := flag.Bool("prune", false, "evict least recently used variants until the cache is within budget")
+	flag.Parse()
+
+	cacheRoot := config.ResolveCacheRoot()
+	fmt.Printf("Cache root: %s\n", cacheRoot)
+
+	// Opening the cache without a budget reports it as it is; pruning is explicit.
+	cache, err := images.OpenCache(cacheRoot, 0)
+	if err != nil {
+		fmt.Printf("Error indexing cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	usage := cache.Usage()
+	fmt.Printf("Files: %d\n", usage.Files)
+	fmt.Printf("Size: %s of %s budget\n", megabytes(usage.Bytes), megabytes(*maxMB<<20))
+	if !usage.Oldest.IsZero() {
+		fmt.Printf("Least recently used: %s\n", usage.Oldest.Format(time.DateTime))
+	}
+
+	if !*prune {
+		return
+	}
+	removed, freed, err := cache.Prune(*maxMB << 20)
+	if err != nil {
+		fmt.Printf("Error pruning cache: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %d files, freeing %s\n", removed, megabytes(freed))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"strings"
+)
+
+// describe returns an image's dimensions and file size for the report.
+func describe(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	size := fmt.Sprintf("%.1f MB", float64(info.Size())/(1<<20))
+
+	file, err := os.Open(path)
+	if err != nil {
+		return size
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return size
+	}
+	return fmt.Sprintf("%dx%d, %s", config.Width, config.Height, size)
+}
+
+func main() {
+	root := flag.String("root", "content/portfolio", "portfolio directory to scan")
+	threshold := flag.Int("threshold", 6, "maximum differing hash bits (0-64) for two photos to count as duplicates")
+	flag.Parse()
+
+	if _, err := os.Stat(*root); os.IsNotExist(err) {
+		fmt.Printf("Error: %s not found. Please run from project root.\n", *root)
+		os.Exit(1)
+	}
+
+	var hashed []images.HashedImage
+	err := filepath.Walk(*root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != *root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Resized variants are expected copies of their originals.
+		name := info.Name()
+		if images.IsVariantName(name) {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			return nil
+		}
+
+		hash, err := images.HashFile(path)
+		if err != nil {
+			fmt.Printf("Skipping %s: %v\n", path, err)
+			return nil
+		}
+		relPath, err := filepath.Rel(*root, path)
+		if err != nil {
+			return err
+		}
+		hashed = append(hashed, images.HashedImage{Path: filepath.ToSlash(relPath), Hash: hash})
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error scanning files: %v\n", err)
+		os.Exit(1)
+	}
+
+	groups := images.GroupDuplicates(hashed, *threshold)
+	fmt.Printf("Scanned %d images in %s; %d duplicate groups at threshold %d.\n", len(hashed), *root, len(groups), *threshold)
+
+	for idx, group := range groups {
+		fmt.Printf("\nGroup %d:\n", idx+1)
+		for _, img := range group {
+			fmt.Printf("  %-50s distance %2d  %s\n", img.Path, img.Hash.Distance(group[0].Hash), describe(filepath.Join(*root, filepath.FromSlash(img.Path))))
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// cleanup removes the files published from sources that are gone, and the
+// stale outputs of those that remain, then returns how many there were. Files
+// are found through the manifest and, for those published before it existed,
+// by mapping their names back to a source. Only files named like something the
+// optimizer writes are ever removed, so nothing else put in the destination is
+// touched. With dryRun the files are listed and the manifest is left as it was.
+func (src source) cleanup(manifest *manifest, sources map[string]bool, dryRun bool) int {
+	// Files still published are kept even if a gone source listed them too,
+	// e.g. a.jpg's variants after a.png was deleted.
+	live := map[string]bool{}
+	for relPath, entry := range manifest.Files {
+		if sources[relPath] {
+			for _, output := range entry.Outputs {
+				for _, file := range derivedFiles(output) {
+					live[file] = true
+				}
+			}
+		}
+	}
+
+	candidates := map[string]bool{}
+	for relPath, entry := range manifest.Files {
+		outputs := entry.Stale
+		if !sources[relPath] {
+			outputs = slices.Concat(entry.Stale, entry.Outputs)
+		}
+		for _, output := range outputs {
+			for _, file := range derivedFiles(output) {
+				if !live[file] && isDerivedPath(file) {
+					candidates[file] = true
+				}
+			}
+		}
+	}
+
+	for _, file := range src.unsourced(sources) {
+		if !live[file] {
+			candidates[file] = true
+		}
+	}
+
+	var orphans []string
+	for file := range candidates {
+		if _, err := os.Lstat(filepath.Join(src.destDir, filepath.FromSlash(file))); err == nil {
+			orphans = append(orphans, file)
+		}
+	}
+	sort.Strings(orphans)
+
+	removed := 0
+	for _, file := range orphans {
+		destPath := filepath.Join(src.destDir, filepath.FromSlash(file))
+		if dryRun {
+			fmt.Printf("Would remove %s\n", destPath)
+			removed++
+			continue
+		}
+		if err := os.Remove(destPath); err != nil {
+			fmt.Printf("Failed to remove %s: %v\n", destPath, err)
+			continue
+		}
+		fmt.Printf("Removed %s\n", destPath)
+		removed++
+		removeEmptyDirs(src.destDir, filepath.Dir(destPath))
+	}
+	if dryRun {
+		return removed
+	}
+
+	// Forget what has been cleaned up. Files that failed to go stay listed
+	// for the next run.
+	for relPath, entry := range manifest.Files {
+		if !sources[relPath] {
+			entry.Stale = slices.Concat(entry.Stale, entry.Outputs)
+			entry.Outputs = nil
+		}
+		entry.Stale = slices.DeleteFunc(entry.Stale, func(output string) bool {
+			_, err := os.Lstat(filepath.Join(src.destDir, filepath.FromSlash(output)))
+			return err != nil || live[output]
+		})
+		if len(entry.Outputs) == 0 && len(entry.Stale) == 0 {
+			delete(manifest.Files, relPath)
+		} else {
+			manifest.Files[relPath] = entry
+		}
+	}
+	return removed
+}
+
+// unsourced walks the destination for files named like something the
+// optimizer writes whose source, going by the name, is gone.
+func (src source) unsourced(sources map[string]bool) []string {
+	// Images are published with lower-case extensions; modern-format copies
+	// only share the source's name without its extension.
+	outputs := map[string]bool{}
+	stems := map[string]bool{}
+	for relPath := range sources {
+		ext := path.Ext(relPath)
+		if isImageExt(ext) {
+			outputs[strings.TrimSuffix(relPath, ext)+strings.ToLower(ext)] = true
+			stems[strings.TrimSuffix(relPath, ext)] = true
+		} else {
+			outputs[relPath] = true
+		}
+	}
+
+	var files []string
+	filepath.WalkDir(src.destDir, func(destPath string, entry fs.DirEntry, err error) error {
+		// Unreadable directories are skipped; only what is seen is removed.
+		if err != nil || destPath == src.destDir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(src.destDir, destPath)
+		if err != nil {
+			return nil
+		}
+		file := filepath.ToSlash(relPath)
+		if isDerivedPath(file) && !hasSource(file, outputs, stems) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files
+}
+
+// hasSource reports whether file is published from one of the sources, going
+// by its name: album settings and sidecars keep theirs, optimized images keep
+// their stem, and variants add a suffix to it.
+func hasSource(file string, outputs, stems map[string]bool) bool {
+	ext := path.Ext(file)
+	modern := slices.ContainsFunc(images.ModernFormats, func(format images.Format) bool {
+		return ext == "."+format.Name
+	})
+	if !isImageExt(ext) && !modern {
+		return outputs[file]
+	}
+	stem := strings.TrimSuffix(file, ext)
+	if outputs[stem+strings.ToLower(ext)] {
+		return true
+	}
+	if source, _, _, ok := images.ParseVariantName(path.Base(file)); ok {
+		stem = path.Join(path.Dir(file), strings.TrimSuffix(source, path.Ext(source)))
+	}
+	if modern {
+		return stems[stem]
+	}
+	return outputs[stem+strings.ToLower(ext)]
+}
+
+// isImageExt reports whether ext is that of an image the optimizer publishes.
+func isImageExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// derivedFiles returns an output and the modern-format copies published
+// alongside it, which only variants have.
+func derivedFiles(output string) []string {
+	files := []string{output}
+	if images.IsVariantName(path.Base(output)) {
+		for _, format := range images.ModernFormats {
+			files = append(files, format.Sibling(output))
+		}
+	}
+	return files
+}
+
+// isDerivedPath guards against a damaged or hand-edited manifest: a file is only
+// removed if it lies inside the destination, is not a dot file such as the
+// manifest, and is named like something the optimizer writes.
+func isDerivedPath(file string) bool {
+	if !filepath.IsLocal(filepath.FromSlash(file)) {
+		return false
+	}
+	for _, segment := range strings.Split(file, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return false
+		}
+	}
+	if isImageExt(path.Ext(file)) || strings.EqualFold(path.Ext(file), ".xmp") {
+		return true
+	}
+	if path.Base(file) == portfolio.AlbumMetadataFile {
+		return true
+	}
+	for _, format := range images.ModernFormats {
+		if path.Ext(file) == "."+format.Name {
+			return images.IsVariantName(path.Base(file))
+		}
+	}
+	return false
+}
+
+// removeEmptyDirs removes dir and its parents, up to but not including root,
+// while they are empty.
+func removeEmptyDirs(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
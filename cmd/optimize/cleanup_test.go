@@ -0,0 +1,278 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"testing"
+)
+
+// testPipeline keeps runs fast and independent of the encoders installed.
+var testPipeline = images.Pipeline{
+	Original: images.VariantSpec{Width: 64},
+	Variants: []images.VariantSpec{{Width: 32}},
+}
+
+// newSource returns an empty source directory and its destination.
+func newSource(t *testing.T) source {
+	t.Helper()
+	root := t.TempDir()
+	src := source{dir: filepath.Join(root, "src"), destDir: filepath.Join(root, "dest"), policyFor: defaultPolicy}
+	if err := os.MkdirAll(src.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// writeFile writes data to the slash-separated relPath under dir.
+func writeFile(t *testing.T, dir, relPath string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writePhoto writes a small photo of one shade to relPath under dir.
+func writePhoto(t *testing.T, dir, relPath string, shade uint8) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img := image.NewNRGBA(image.Rect(0, 0, 96, 64))
+	for idx := range img.Pix {
+		img.Pix[idx] = shade
+	}
+	img.Set(0, 0, color.NRGBA{A: 255})
+	if err := jpeg.Encode(file, img, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertFiles checks which of the slash-separated files exist under dir.
+func assertFiles(t *testing.T, dir string, want map[string]bool) {
+	t.Helper()
+	for relPath, exists := range want {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if exists && err != nil {
+			t.Errorf("expected %s to exist: %v", relPath, err)
+		}
+		if !exists && err == nil {
+			t.Errorf("expected %s to be removed", relPath)
+		}
+	}
+}
+
+func TestOptimizeDir_RemovesDeletedSource(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "Alaska/bear.jpg", 100)
+	writePhoto(t, src.dir, "Alaska/moose.jpg", 150)
+	writeFile(t, src.dir, "Alaska/moose.xmp", []byte("<x:xmpmeta/>"))
+	optimizeDir(src, testPipeline, 2, false)
+
+	os.Remove(filepath.Join(src.dir, "Alaska", "moose.jpg"))
+	os.Remove(filepath.Join(src.dir, "Alaska", "moose.xmp"))
+	if result := optimizeDir(src, testPipeline, 2, false); result.Orphans != 3 {
+		t.Errorf("expected 3 orphans, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{
+		"Alaska/bear.jpg":      true,
+		"Alaska/bear_w32.jpg":  true,
+		"Alaska/moose.jpg":     false,
+		"Alaska/moose_w32.jpg": false,
+		"Alaska/moose.xmp":     false,
+	})
+	if _, ok := loadManifest(src.destDir).Files["Alaska/moose.jpg"]; ok {
+		t.Error("expected the deleted source to be dropped from the manifest")
+	}
+
+	os.Remove(filepath.Join(src.dir, "Alaska", "bear.jpg"))
+	writePhoto(t, src.dir, "Wildlife/eagle.jpg", 50)
+	optimizeDir(src, testPipeline, 2, false)
+	if _, err := os.Stat(filepath.Join(src.destDir, "Alaska")); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied album directory to be removed, got %v", err)
+	}
+}
+
+func TestOptimizeDir_KeepsOutputsSharedWithLiveSource(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "a.jpg", 100)
+	writePhoto(t, src.dir, "a.png", 100)
+	optimizeDir(src, testPipeline, 1, false)
+	// Both sources' variants would have the same WebP copy.
+	writeFile(t, src.destDir, "a_w32.webp", []byte("RIFF"))
+
+	os.Remove(filepath.Join(src.dir, "a.png"))
+	optimizeDir(src, testPipeline, 1, false)
+	assertFiles(t, src.destDir, map[string]bool{
+		"a.jpg":      true,
+		"a_w32.jpg":  true,
+		"a_w32.webp": true,
+		"a.png":      false,
+		"a_w32.png":  false,
+	})
+}
+
+func TestOptimizeDir_RemovesOrphansPublishedBeforeManifest(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "keep.JPG", 100)
+	for _, name := range []string{"old.png", "old_w600.png", "old_w600_3x2.png", "old_w600.webp", "keep_w1200.jpg", "keep_w600.avif", "album.yaml"} {
+		writeFile(t, src.destDir, name, []byte("published"))
+	}
+
+	if result := optimizeDir(src, testPipeline, 1, false); result.Orphans != 5 {
+		t.Errorf("expected 5 orphans, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{
+		"keep.jpg":         true,
+		"keep_w1200.jpg":   true,
+		"keep_w600.avif":   true,
+		"old.png":          false,
+		"old_w600.png":     false,
+		"old_w600_3x2.png": false,
+		"old_w600.webp":    false,
+		"album.yaml":       false,
+	})
+}
+
+func TestOptimizeDir_LeavesOtherFilesAlone(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "a.jpg", 100)
+	writePhoto(t, src.dir, "b.jpg", 100)
+	optimizeDir(src, testPipeline, 1, false)
+	for _, name := range []string{"README.md", "CNAME", ".nojekyll", "b_w32.webp.bak", ".hidden/b.jpg"} {
+		writeFile(t, src.destDir, name, []byte("kept"))
+	}
+
+	os.Remove(filepath.Join(src.dir, "b.jpg"))
+	optimizeDir(src, testPipeline, 1, false)
+	assertFiles(t, src.destDir, map[string]bool{
+		"README.md":      true,
+		"CNAME":          true,
+		".nojekyll":      true,
+		"b_w32.webp.bak": true,
+		".hidden/b.jpg":  true,
+		"b.jpg":          false,
+	})
+}
+
+func TestOptimizeDir_IgnoresManifestPathsOutsideDestination(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "a.jpg", 100)
+	outside := filepath.Join(filepath.Dir(src.destDir), "x.jpg")
+	writeFile(t, filepath.Dir(src.destDir), "x.jpg", []byte("not ours"))
+
+	manifest := loadManifest(src.destDir)
+	manifest.Files["gone.jpg"] = manifestEntry{Source: "0", Outputs: []string{"../x.jpg", outside, "./../x.jpg", ".manifest.json"}}
+	if err := manifest.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	optimizeDir(src, testPipeline, 1, false)
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected the file outside the destination to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src.destDir, manifestFile)); err != nil {
+		t.Errorf("expected the manifest to be kept: %v", err)
+	}
+}
+
+func TestOptimizeDir_SkipsCleanupWithoutFullSourceListing(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "Alaska/a.jpg", 100)
+	optimizeDir(src, testPipeline, 1, false)
+	writeFile(t, src.destDir, "Alaska/old.jpg", []byte("published"))
+
+	// An empty source directory, such as an unmounted volume.
+	os.RemoveAll(filepath.Join(src.dir, "Alaska"))
+	if result := optimizeDir(src, testPipeline, 1, false); result.Orphans != 0 {
+		t.Errorf("expected no cleanup of an empty source, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{"Alaska/a.jpg": true, "Alaska/old.jpg": true})
+
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions do not apply to root")
+	}
+	writePhoto(t, src.dir, "Alaska/a.jpg", 100)
+	writePhoto(t, src.dir, "Wildlife/b.jpg", 100)
+	wildlife := filepath.Join(src.dir, "Wildlife")
+	if err := os.Chmod(wildlife, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(wildlife, 0755)
+	if result := optimizeDir(src, testPipeline, 1, false); result.Orphans != 0 {
+		t.Errorf("expected no cleanup with an unreadable album, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{"Alaska/old.jpg": true})
+}
+
+func TestOptimizeDir_DryRunRemovesNothing(t *testing.T) {
+	src := newSource(t)
+	writePhoto(t, src.dir, "a.jpg", 100)
+	writePhoto(t, src.dir, "b.jpg", 150)
+	optimizeDir(src, testPipeline, 1, false)
+	writeFile(t, src.destDir, "old.jpg", []byte("published"))
+
+	os.Remove(filepath.Join(src.dir, "b.jpg"))
+	if result := optimizeDir(src, testPipeline, 1, true); result.Orphans != 3 {
+		t.Errorf("expected 3 files listed, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{"b.jpg": true, "b_w32.jpg": true, "old.jpg": true})
+	if _, ok := loadManifest(src.destDir).Files["b.jpg"]; !ok {
+		t.Error("expected the dry run to leave the manifest as it was")
+	}
+
+	if result := optimizeDir(src, testPipeline, 1, false); result.Orphans != 3 {
+		t.Errorf("expected the listed files to be removed afterwards, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{"a.jpg": true, "b.jpg": false, "b_w32.jpg": false, "old.jpg": false})
+}
+
+func TestOptimizeDir_DryRunPublishesNothing(t *testing.T) {
+	src := newSource(t)
+	src.accessFor = portfolioAccess(src.dir)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 100)
+	writePhoto(t, src.dir, "Clients/wedding.jpg", 100)
+	writePhoto(t, src.dir, ".branding/mark.png", 255)
+	writeFile(t, src.dir, "Clients/album.yaml", []byte("private: true\npassword_hash: secret\n"))
+	writeFile(t, src.dir, "Wildlife/bear.xmp", []byte("<x:xmpmeta/>"))
+
+	if result := optimizeDir(src, testPipeline, 1, true); result != (summary{Processed: 2}) {
+		t.Errorf("expected both photos listed, got %+v", result)
+	}
+	if _, err := os.Stat(src.destDir); !os.IsNotExist(err) {
+		t.Errorf("expected the dry run to write nothing, got %v", err)
+	}
+
+	optimizeDir(src, testPipeline, 1, false)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 200)
+	manifest, err := os.ReadFile(filepath.Join(src.destDir, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(filepath.Join(src.destDir, "Wildlife", "bear.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := optimizeDir(src, testPipeline, 1, true); result != (summary{Processed: 1, Skipped: 1}) {
+		t.Errorf("expected the changed photo listed, got %+v", result)
+	}
+	if after, err := os.ReadFile(filepath.Join(src.destDir, manifestFile)); err != nil || string(after) != string(manifest) {
+		t.Errorf("expected the dry run to leave the manifest as it was, got %v", err)
+	}
+	if after, err := os.ReadFile(filepath.Join(src.destDir, "Wildlife", "bear.jpg")); err != nil || string(after) != string(published) {
+		t.Errorf("expected the dry run to leave the published photo as it was, got %v", err)
+	}
+}
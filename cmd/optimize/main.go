@@ -1,138 +1,525 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"image"
-	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
+	"runtime"
 	"strings"
-
-	"github.com/disintegration/imaging"
+	"sync"
+	"time"
 )
 
-func optimizeDir(sourceDir, destDir string, quality int) {
-	fmt.Printf("Optimizing %s -> %s\n", sourceDir, destDir)
+// isNewer reports whether destPath is missing or older than modTime.
+func isNewer(modTime time.Time, destPath string) bool {
+	destInfo, err := os.Stat(destPath)
+	return err != nil || !modTime.Before(destInfo.ModTime())
+}
 
-	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
-		fmt.Printf("Warning: Source directory %s not found\n", sourceDir)
-		return
+// copyIfNewer copies album settings and watermark images across unchanged.
+func copyIfNewer(srcPath, destPath string, srcInfo os.FileInfo) error {
+	if !isNewer(srcInfo.ModTime(), destPath) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
 	}
 
-	// 1. Process new/modified files
-	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// writeSidecarIfNewer publishes only the keywords of an .xmp sidecar, which may
+// otherwise carry a location or other private fields.
+func writeSidecarIfNewer(srcPath, destPath string, srcInfo os.FileInfo) error {
+	if !isNewer(srcInfo.ModTime(), destPath) {
+		return nil
+	}
+	keywords, err := images.ReadSidecarKeywords(srcPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(destPath, images.KeywordsXMP(keywords), 0644)
+}
+
+// metadataSegments returns the application segments of a JPEG source so the
+// metadata policy can carry what it allows over to the optimized copies (albums
+// read capture times, keywords and locations from them).
+func metadataSegments(path string) []images.Segment {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	segments, err := images.ReadSegments(file)
+	if err != nil {
+		return nil
+	}
+	return segments
+}
+
+// policyFunc returns the metadata policy for an image, by path relative to the source directory.
+type policyFunc func(relPath string) (images.MetadataPolicy, error)
+
+// portfolioPolicy follows each album's location and metadata settings.
+func portfolioPolicy(sourceDir string) policyFunc {
+	return func(relPath string) (images.MetadataPolicy, error) {
+		return portfolio.LoadMetadataPolicy(sourceDir, filepath.ToSlash(filepath.Dir(relPath)))
+	}
+}
+
+// accessFunc returns who may view an album, by slash-separated path relative
+// to the source directory.
+type accessFunc func(albumPath string) (portfolio.Access, error)
+
+// portfolioAccess follows each album's private setting.
+func portfolioAccess(sourceDir string) accessFunc {
+	return func(albumPath string) (portfolio.Access, error) {
+		return portfolio.LoadAccess(sourceDir, albumPath)
+	}
+}
+
+// privateIgnore is written into the published copy of each private album so
+// git never picks up its photos or its album.yaml, which holds the password hash.
+const privateIgnore = "# Private album published by cmd/optimize; never commit it.\n*\n"
+
+// ignorePrivate keeps the published copy of the album at relPath out of git
+// while it is private, and lets git see it again once it is not.
+func (src source) ignorePrivate(relPath string) error {
+	access, err := src.accessFor(filepath.ToSlash(relPath))
+	if err != nil {
+		return err
+	}
+	ignorePath := filepath.Join(src.destDir, relPath, ".gitignore")
+	if access.IsPrivate() {
+		if err := os.MkdirAll(filepath.Dir(ignorePath), 0755); err != nil {
 			return err
 		}
-		if info.IsDir() {
-			return nil
+		return os.WriteFile(ignorePath, []byte(privateIgnore), 0644)
+	}
+	if data, err := os.ReadFile(ignorePath); err == nil && string(data) == privateIgnore {
+		return os.Remove(ignorePath)
+	}
+	return nil
+}
+
+// defaultPolicy publishes the default EXIF fields and never a location.
+func defaultPolicy(string) (images.MetadataPolicy, error) {
+	return images.MetadataPolicy{}, nil
+}
+
+// encodeModernFormats publishes the variant at destPath in each of formats
+// whose encoder is installed. EncodeSibling skips copies that are up to date.
+func encodeModernFormats(destPath string, formats []images.Format) {
+	for _, format := range formats {
+		if !format.Available() {
+			continue
+		}
+		if _, err := format.EncodeSibling(destPath); err != nil {
+			fmt.Printf("Failed to encode %s as %s: %v\n", destPath, format.Name, err)
 		}
+	}
+}
 
-		// check extension
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-			return nil
+// source is a directory of images and how to publish them.
+type source struct {
+	dir, destDir string
+	policyFor    policyFunc
+	accessFor    accessFunc           // nil has no private albums
+	watermarkFor images.WatermarkFunc // nil draws no watermarks
+	focusFor     images.FocusFunc     // nil builds no cropped variants
+}
+
+// target is one file an image is published as: the optimized original, with
+// no suffix, or one of the pipeline's variants.
+type target struct {
+	suffix string
+	spec   images.VariantSpec
+}
+
+// outcome is what became of one image.
+type outcome int
+
+const (
+	processed outcome = iota
+	skipped
+	failed
+)
+
+// summary counts the images of a run by outcome, and the orphaned files
+// cleanup found.
+type summary struct {
+	Processed, Skipped, Failed int
+	Orphans                    int
+}
+
+func (s *summary) add(result outcome) {
+	switch result {
+	case processed:
+		s.Processed++
+	case skipped:
+		s.Skipped++
+	case failed:
+		s.Failed++
+	}
+}
+
+func (s *summary) merge(other summary) {
+	s.Processed += other.Processed
+	s.Skipped += other.Skipped
+	s.Failed += other.Failed
+	s.Orphans += other.Orphans
+}
+
+func (s summary) describe(dryRun bool) string {
+	processed, orphans := "processed", "removed"
+	if dryRun {
+		processed, orphans = "would be processed", "would be removed"
+	}
+	return fmt.Sprintf("%d %s, %d skipped, %d failed; %d orphaned files %s", s.Processed, processed, s.Skipped, s.Failed, s.Orphans, orphans)
+}
+
+// optimizeDir publishes the images under src.dir to src.destDir as pipeline
+// declares them, with workers images in flight at once, then removes what was
+// published from sources that are gone. With dryRun nothing is written or
+// removed: the images that would be published and the files that would go are
+// only listed.
+func optimizeDir(src source, pipeline images.Pipeline, workers int, dryRun bool) summary {
+	var result summary
+	fmt.Printf("Optimizing %s -> %s\n", src.dir, src.destDir)
+
+	if _, err := os.Stat(src.dir); os.IsNotExist(err) {
+		fmt.Printf("Warning: Source directory %s not found\n", src.dir)
+		return result
+	}
+
+	// 1. Copy album settings and sidecars, and list the images
+	manifest := loadManifest(src.destDir)
+	var relPaths []string
+	sources := map[string]bool{}
+	err := filepath.Walk(src.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
 
 		// calculate relative path
-		relPath, err := filepath.Rel(sourceDir, path)
+		relPath, err := filepath.Rel(src.dir, path)
 		if err != nil {
 			return err
 		}
-
-		// Optimization targets
-		// 1. Original (optimized) - used as fallback
-		// 2. w600 - for grids
-		// 3. w1600 - for lightbox
-		targets := []struct {
-			suffix string
-			width  int
-		}{
-			{suffix: "", width: 2500},       // Base image, max 2500
-			{suffix: "_w600", width: 600},   // Grid thumbnail
-			{suffix: "_w1600", width: 1600}, // Lightbox view
-		}
-
-		for _, target := range targets {
-			// Construct destination filename
-			// e.g., image.jpg -> image.jpg (base)
-			// e.g., image.jpg -> image_w600.jpg
-			baseName := strings.TrimSuffix(relPath, filepath.Ext(relPath))
-			destName := baseName + target.suffix + ext
-			destPath := filepath.Join(destDir, destName)
-			destDir := filepath.Dir(destPath)
-
-			// Create dest dir
-			if err := os.MkdirAll(destDir, 0755); err != nil {
-				return err
+		branding := strings.HasPrefix(filepath.ToSlash(relPath)+"/", portfolio.BrandingDir+"/")
+		if info.IsDir() {
+			if relPath == "." || branding {
+				return nil
 			}
-
-			// Check if needs update
-			needsUpdate := true
-			if destInfo, err := os.Stat(destPath); err == nil {
-				if info.ModTime().Before(destInfo.ModTime()) {
-					needsUpdate = false
-				}
+			// Other dot directories are not albums.
+			if strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
 			}
-
-			if !needsUpdate {
-				continue
+			if src.accessFor == nil || dryRun {
+				return nil
 			}
+			return src.ignorePrivate(relPath)
+		}
 
-			fmt.Printf("Processing %s (%s)... ", relPath, target.suffix)
-
-			// Open image (only once ideally, but simple loop here)
-			src, err := imaging.Open(path)
-			if err != nil {
-				fmt.Printf("Failed to open: %v\n", err)
+		// Watermark images are copied as they are for the server to draw.
+		if branding {
+			if dryRun {
 				return nil
 			}
+			return copyIfNewer(path, filepath.Join(src.destDir, relPath), info)
+		}
 
-			// Resize
-			var dst *image.NRGBA
-			if src.Bounds().Dx() > target.width {
-				dst = imaging.Resize(src, target.width, 0, imaging.Lanczos)
-			} else {
-				dst = imaging.Clone(src)
+		var copyErr error
+		switch {
+		case dryRun && (info.Name() == portfolio.AlbumMetadataFile || strings.ToLower(filepath.Ext(path)) == ".xmp"):
+		case info.Name() == portfolio.AlbumMetadataFile:
+			copyErr = copyIfNewer(path, filepath.Join(src.destDir, relPath), info)
+		case strings.ToLower(filepath.Ext(path)) == ".xmp":
+			copyErr = writeSidecarIfNewer(path, filepath.Join(src.destDir, relPath), info)
+		default:
+			// check extension
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext == ".jpg" || ext == ".jpeg" || ext == ".png" {
+				relPaths = append(relPaths, relPath)
+				sources[filepath.ToSlash(relPath)] = true
 			}
+			return nil
+		}
+		if copyErr != nil {
+			return copyErr
+		}
+		// Copies are recorded too, so cleanup removes them with their album.
+		manifest.record(relPath, manifestEntry{Outputs: []string{filepath.ToSlash(relPath)}})
+		sources[filepath.ToSlash(relPath)] = true
+		return nil
+	})
 
-			// Save
-			file, err := os.Create(destPath)
-			if err != nil {
-				fmt.Printf("Failed to create file: %v\n", err)
-				continue
-			}
+	if err != nil {
+		fmt.Printf("Error walking source dir: %v\n", err)
+		result.Failed++
+	}
 
-			if ext == ".png" {
-				err = png.Encode(file, dst)
-			} else {
-				err = jpeg.Encode(file, dst, &jpeg.Options{Quality: quality})
+	// 2. Publish new and changed images
+	jobs := make(chan string)
+	results := make(chan outcome)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relPath := range jobs {
+				results <- src.optimizeImage(relPath, pipeline, manifest, dryRun)
 			}
-			file.Close()
+		}()
+	}
+	go func() {
+		for _, relPath := range relPaths {
+			jobs <- relPath
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+	for done := range results {
+		result.add(done)
+	}
 
-			if err != nil {
-				fmt.Printf("Failed to save: %v\n", err)
-			} else {
-				fmt.Println("Done")
-			}
+	// 3. Remove what was published from sources that are gone. A partial
+	// listing of the sources would make everything else look orphaned.
+	switch {
+	case err != nil:
+		fmt.Printf("Skipping cleanup of %s: the source listing is incomplete\n", src.destDir)
+	case len(sources) == 0:
+		fmt.Printf("Skipping cleanup of %s: %s is empty; is it mounted?\n", src.destDir, src.dir)
+	default:
+		result.Orphans = src.cleanup(manifest, sources, dryRun)
+	}
+
+	if !dryRun {
+		if err := manifest.save(); err != nil {
+			fmt.Printf("Failed to save manifest: %v\n", err)
 		}
+	}
+	fmt.Printf("%s: %s\n", src.dir, result.describe(dryRun))
+	return result
+}
 
-		return nil
-	})
+// optimizeImage publishes one image unless the manifest shows it unchanged.
+// The original is decoded once for all of its targets. With dryRun it is only
+// listed, though the manifest in memory is updated for cleanup to list the
+// outputs it would replace.
+func (src source) optimizeImage(relPath string, pipeline images.Pipeline, manifest *manifest, dryRun bool) outcome {
+	path := filepath.Join(src.dir, relPath)
+	ext := strings.ToLower(filepath.Ext(relPath))
+
+	// Optimization targets
+	// 1. Original (optimized) - used as fallback
+	// 2. The pipeline's variants - for grids, lightbox, posts and category
+	//    cards, which are cropped around the focal point
+	targets := []target{{suffix: "", spec: pipeline.Original}}
+	for _, spec := range pipeline.Variants {
+		if spec.Aspect != nil && src.focusFor == nil {
+			continue
+		}
+		targets = append(targets, target{suffix: spec.Suffix(), spec: spec})
+	}
 
+	var err error
+	var focus *images.FocalPoint
+	if src.focusFor != nil {
+		if focus, err = src.focusFor(relPath); err != nil {
+			fmt.Printf("Failed to load focal point for %s: %v\n", relPath, err)
+			return failed
+		}
+	}
+	policy, err := src.policyFor(relPath)
 	if err != nil {
-		fmt.Printf("Error walking source dir: %v\n", err)
+		fmt.Printf("Failed to load metadata policy for %s: %v\n", relPath, err)
+		return failed
+	}
+	var watermark images.Watermark
+	if src.watermarkFor != nil {
+		if watermark, err = src.watermarkFor(relPath); err != nil {
+			fmt.Printf("Failed to load watermark for %s: %v\n", relPath, err)
+			return failed
+		}
 	}
 
-	// 2. Cleanup (Simplified for now - just manual for safety in this change)
+	entry := manifestEntry{}
+	if entry.Source, err = fileHash(path); err != nil {
+		fmt.Printf("Failed to read %s: %v\n", relPath, err)
+		return failed
+	}
+	if entry.Inputs, err = inputsHash(targets, policy, watermark, focus); err != nil {
+		fmt.Printf("Failed to load watermark for %s: %v\n", relPath, err)
+		return failed
+	}
+	// e.g., image.jpg -> image.jpg (base)
+	// e.g., image.jpg -> image_w600.jpg
+	baseName := strings.TrimSuffix(filepath.ToSlash(relPath), filepath.Ext(relPath))
+	for _, target := range targets {
+		entry.Outputs = append(entry.Outputs, baseName+target.suffix+ext)
+	}
+
+	if manifest.upToDate(relPath, entry) {
+		if dryRun {
+			return skipped
+		}
+		// Encoders installed since the last run still get their copies made.
+		for idx, target := range targets {
+			encodeModernFormats(filepath.Join(src.destDir, filepath.FromSlash(entry.Outputs[idx])), target.spec.Formats)
+		}
+		return skipped
+	}
+	if dryRun {
+		manifest.record(relPath, entry)
+		fmt.Printf("Would process %s\n", relPath)
+		return processed
+	}
+
+	original, err := images.Open(path)
+	if err != nil {
+		fmt.Printf("Failed to open %s: %v\n", relPath, err)
+		return failed
+	}
+	segments := metadataSegments(path)
+	profile := images.ProfileSegments(path)
+
+	for idx, target := range targets {
+		destPath := filepath.Join(src.destDir, filepath.FromSlash(entry.Outputs[idx]))
+
+		// Estimate the focal point once for all of the image's crops.
+		if target.spec.Aspect != nil && focus == nil {
+			estimate := images.EntropyFocus(original)
+			focus = &estimate
+		}
+
+		// The base image is the source of every variant the server resizes,
+		// so only the prebuilt variants are watermarked here.
+		targetWatermark := watermark
+		// Only the base image may carry a location; variants never do.
+		targetPolicy := policy.ForVariant()
+		if target.suffix == "" {
+			targetWatermark = images.Watermark{}
+			targetPolicy = policy
+		}
+
+		dst, err := target.spec.Render(original, focus, targetWatermark)
+		if err != nil {
+			fmt.Printf("Failed to render %s: %v\n", destPath, err)
+			return failed
+		}
+		err = writeImage(destPath, dst, target.spec.JPEGQuality(), append(targetPolicy.FilterSegments(segments), profile...))
+		if err != nil {
+			fmt.Printf("Failed to save %s: %v\n", destPath, err)
+			return failed
+		}
+		encodeModernFormats(destPath, target.spec.Formats)
+	}
+
+	manifest.record(relPath, entry)
+	fmt.Printf("Processed %s\n", relPath)
+	return processed
+}
+
+// inputsHash hashes what an image's outputs depend on besides the image itself.
+// Watermark images are hashed by content, like sources.
+func inputsHash(targets []target, policy images.MetadataPolicy, watermark images.Watermark, focus *images.FocalPoint) (string, error) {
+	hash := sha256.New()
+	for _, target := range targets {
+		spec := target.spec
+		fmt.Fprintf(hash, "%q %d %d %g", target.suffix, spec.Width, spec.JPEGQuality(), spec.Sharpen)
+		for _, format := range spec.Formats {
+			fmt.Fprintf(hash, " %s", format.Name)
+		}
+		fmt.Fprintln(hash)
+	}
+	fmt.Fprintf(hash, "%+v\n%+v\n", policy, watermark)
+	if watermark.Image != "" {
+		markHash, err := fileHash(watermark.Image)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(hash, markHash)
+	}
+	if focus != nil {
+		fmt.Fprintf(hash, "%+v\n", *focus)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeImage saves img to destPath as a PNG or, with segments, a JPEG. It
+// writes to a temporary file first so the server never reads a partial image.
+func writeImage(destPath string, img image.Image, quality int, segments []images.Segment) error {
+	destDir := filepath.Dir(destPath)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(destDir, "."+filepath.Base(destPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if strings.EqualFold(filepath.Ext(destPath), ".png") {
+		err = png.Encode(tmp, img)
+	} else {
+		err = images.WriteJPEG(tmp, img, quality, segments)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), destPath)
 }
 
 func main() {
-	jpegQuality := 85
+	workers := flag.Int("workers", runtime.NumCPU(), "images to optimize at once")
+	dryRun := flag.Bool("dry-run", false, "list the images that would be published and the orphaned files that would be removed, writing nothing")
+	flag.Parse()
 
-	optimizeDir("content/portfolio", "content/portfolio_optimized", jpegQuality)
-	optimizeDir("content/aboutme", "content/aboutme_optimized", jpegQuality)
+	for _, format := range images.ModernFormats {
+		if !format.Available() {
+			fmt.Printf("Note: %s variants are skipped; the encoder is not installed\n", format.Name)
+		}
+	}
+
+	var total summary
+	for _, src := range []source{
+		{dir: "content/portfolio", destDir: "content/portfolio_optimized", policyFor: portfolioPolicy("content/portfolio"), accessFor: portfolioAccess("content/portfolio"), watermarkFor: portfolio.Watermarks("content/portfolio"), focusFor: portfolio.FocalPoints("content/portfolio")},
+		{dir: "content/aboutme", destDir: "content/aboutme_optimized", policyFor: defaultPolicy},
+	} {
+		total.merge(optimizeDir(src, images.DefaultPipeline, *workers, *dryRun))
+	}
 
-	fmt.Println("Optimization complete.")
+	fmt.Printf("Optimization complete: %s.\n", total.describe(*dryRun))
+	if total.Failed > 0 {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
+	"testing"
+	"time"
+)
+
+func TestOptimizeDir_PublishesWithWorkerPool(t *testing.T) {
+	src := newSource(t)
+	for idx := range 8 {
+		writePhoto(t, src.dir, fmt.Sprintf("Album%d/photo%d.jpg", idx%3, idx), uint8(30*idx))
+	}
+	writeFile(t, src.dir, "Album0/broken.jpg", []byte("not a jpeg"))
+
+	result := optimizeDir(src, testPipeline, 4, false)
+	if result != (summary{Processed: 8, Failed: 1}) {
+		t.Errorf("expected 8 processed and 1 failed, got %+v", result)
+	}
+	for idx := range 8 {
+		assertFiles(t, src.destDir, map[string]bool{
+			fmt.Sprintf("Album%d/photo%d.jpg", idx%3, idx):     true,
+			fmt.Sprintf("Album%d/photo%d_w32.jpg", idx%3, idx): true,
+		})
+	}
+
+	if result := optimizeDir(src, testPipeline, 4, false); result != (summary{Skipped: 8, Failed: 1}) {
+		t.Errorf("expected unchanged photos to be skipped, got %+v", result)
+	}
+}
+
+func TestOptimizeDir_SkipsUnchangedContent(t *testing.T) {
+	src := newSource(t)
+	src.focusFor = portfolio.FocalPoints(src.dir)
+	writePhoto(t, src.dir, "Alaska/bear.jpg", 100)
+	writePhoto(t, src.dir, "Alaska/moose.jpg", 150)
+	optimizeDir(src, testPipeline, 2, false)
+
+	// A checkout touches modification times without changing content.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(src.dir, "Alaska", "bear.jpg"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if result := optimizeDir(src, testPipeline, 2, false); result != (summary{Skipped: 2}) {
+		t.Errorf("expected a touched photo to be skipped, got %+v", result)
+	}
+
+	writePhoto(t, src.dir, "Alaska/bear.jpg", 200)
+	if result := optimizeDir(src, testPipeline, 2, false); result != (summary{Processed: 1, Skipped: 1}) {
+		t.Errorf("expected changed bytes to re-publish the photo, got %+v", result)
+	}
+
+	writeFile(t, src.dir, "Alaska/album.yaml", []byte("photos:\n  moose.jpg:\n    focus: [0.2, 0.3]\n"))
+	if result := optimizeDir(src, testPipeline, 2, false); result != (summary{Processed: 1, Skipped: 1}) {
+		t.Errorf("expected changed album settings to re-publish the photo, got %+v", result)
+	}
+}
+
+func TestOptimizeDir_KeepsPrivateAlbumsOutOfGit(t *testing.T) {
+	src := newSource(t)
+	src.accessFor = portfolioAccess(src.dir)
+	writePhoto(t, src.dir, "Clients/Smith/wedding.jpg", 100)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 100)
+	writeFile(t, src.dir, "Clients/album.yaml", []byte("private: true\npassword_hash: secret\n"))
+	optimizeDir(src, testPipeline, 1, false)
+
+	ignore, err := os.ReadFile(filepath.Join(src.destDir, "Clients", ".gitignore"))
+	if err != nil || string(ignore) != privateIgnore {
+		t.Errorf("expected the private album to be ignored by git, got %q, %v", ignore, err)
+	}
+	assertFiles(t, src.destDir, map[string]bool{
+		"Clients/Smith/wedding.jpg": true,
+		"Clients/album.yaml":        true,
+		"Wildlife/.gitignore":       false,
+	})
+
+	writeFile(t, src.dir, "Clients/album.yaml", []byte("private: false\n"))
+	optimizeDir(src, testPipeline, 1, false)
+	assertFiles(t, src.destDir, map[string]bool{"Clients/.gitignore": false})
+}
+
+func TestOptimizeDir_SkipsDotDirectories(t *testing.T) {
+	src := newSource(t)
+	src.accessFor = portfolioAccess(src.dir)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 100)
+	writePhoto(t, src.dir, ".branding/mark.png", 200)
+	writePhoto(t, src.dir, "Wildlife/.trash/old.jpg", 50)
+
+	if result := optimizeDir(src, testPipeline, 1, false); result != (summary{Processed: 1}) {
+		t.Errorf("expected only the album photo to be published, got %+v", result)
+	}
+	assertFiles(t, src.destDir, map[string]bool{
+		"Wildlife/bear_w32.jpg":   true,
+		".branding/mark.png":      true,
+		".branding/mark_w32.png":  false,
+		"Wildlife/.trash/old.jpg": false,
+	})
+}
+
+func TestOptimizeDir_WatermarksWithBrandingMark(t *testing.T) {
+	src := newSource(t)
+	src.accessFor = portfolioAccess(src.dir)
+	src.watermarkFor = portfolio.Watermarks(src.dir)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 100)
+	writePhoto(t, src.dir, ".branding/mark.png", 255)
+	writeFile(t, src.dir, "Wildlife/album.yaml", []byte("watermark:\n  image: ../.branding/mark.png\n  opacity: 1\n"))
+
+	// The README's layout, with a variant wide enough to be marked.
+	marked := images.Pipeline{Original: images.VariantSpec{Width: 2500}, Variants: []images.VariantSpec{{Width: images.WatermarkMinWidth}}}
+	if result := optimizeDir(src, marked, 1, false); result != (summary{Processed: 1}) {
+		t.Fatalf("expected the photo alone to be published, got %+v", result)
+	}
+	mark, err := os.ReadFile(filepath.Join(src.dir, ".branding", "mark.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published, err := os.ReadFile(filepath.Join(src.destDir, ".branding", "mark.png")); err != nil || string(published) != string(mark) {
+		t.Errorf("expected the mark to be copied as it is, got %v", err)
+	}
+	// The white mark sits in the bottom-right corner of the grey photo.
+	for name, wantMarked := range map[string]bool{"bear.jpg": false, fmt.Sprintf("bear_w%d.jpg", images.WatermarkMinWidth): true} {
+		img, err := images.Open(filepath.Join(src.destDir, "Wildlife", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bounds := img.Bounds()
+		r, _, _, _ := img.At(bounds.Max.X-4, bounds.Max.Y-4).RGBA()
+		if marked := r>>8 > 200; marked != wantMarked {
+			t.Errorf("%s: expected watermark %v, got corner red %d", name, wantMarked, r>>8)
+		}
+	}
+}
+
+func TestOptimizeDir_CountsWalkErrorsAsFailures(t *testing.T) {
+	src := newSource(t)
+	src.accessFor = portfolioAccess(src.dir)
+	writePhoto(t, src.dir, "Wildlife/bear.jpg", 100)
+	writeFile(t, src.dir, "Wildlife/album.yaml", []byte("private: [unclosed\n"))
+
+	if result := optimizeDir(src, testPipeline, 1, false); result.Failed != 1 {
+		t.Errorf("expected an incomplete walk to count as a failure, got %+v", result)
+	}
+}
+
+func TestSummary_Describe(t *testing.T) {
+	var total summary
+	total.add(processed)
+	total.add(skipped)
+	total.add(skipped)
+	total.add(failed)
+	total.merge(summary{Processed: 1, Orphans: 2})
+
+	if got := total.describe(false); got != "2 processed, 2 skipped, 1 failed; 2 orphaned files removed" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if got := total.describe(true); got != "2 would be processed, 2 skipped, 1 failed; 2 orphaned files would be removed" {
+		t.Errorf("unexpected dry-run summary %q", got)
+	}
+}
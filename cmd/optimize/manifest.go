@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// manifestFile records, in each destination directory, what every optimized
+// image was made from, so sources are skipped while their content and settings
+// are unchanged, whatever their modification times say.
+const manifestFile = ".manifest.json"
+
+// manifestVersion is bumped when the optimizer changes what it writes, which
+// re-publishes every image once.
+const manifestVersion = 1
+
+type manifest struct {
+	dir string
+
+	mu      sync.Mutex
+	Version int                      `json:"version"`
+	Files   map[string]manifestEntry `json:"files"` // by source path relative to the source directory
+}
+
+type manifestEntry struct {
+	// Source is the SHA-256 of the source file.
+	Source string `json:"source"`
+	// Inputs hashes everything else the outputs depend on: the pipeline,
+	// metadata policy, watermark and focal point.
+	Inputs string `json:"inputs"`
+	// Outputs lists the files written, relative to the destination directory.
+	Outputs []string `json:"outputs"`
+	// Stale lists outputs of earlier runs that are no longer written, such as
+	// variants dropped from the pipeline, until cleanup removes them.
+	Stale []string `json:"stale,omitempty"`
+}
+
+// loadManifest reads the manifest in destDir. A missing, damaged or outdated
+// manifest only costs re-publishing the images it described.
+func loadManifest(destDir string) *manifest {
+	m := &manifest{dir: destDir, Version: manifestVersion, Files: map[string]manifestEntry{}}
+	data, err := os.ReadFile(filepath.Join(destDir, manifestFile))
+	if err != nil {
+		return m
+	}
+	var saved manifest
+	if json.Unmarshal(data, &saved) == nil && saved.Version == manifestVersion && saved.Files != nil {
+		m.Files = saved.Files
+	}
+	return m
+}
+
+// upToDate reports whether the source was last published with the same
+// content and inputs, and its outputs are all still there.
+func (m *manifest) upToDate(relPath string, entry manifestEntry) bool {
+	m.mu.Lock()
+	recorded, ok := m.Files[filepath.ToSlash(relPath)]
+	m.mu.Unlock()
+	if !ok || recorded.Source != entry.Source || recorded.Inputs != entry.Inputs || len(recorded.Outputs) != len(entry.Outputs) {
+		return false
+	}
+	for idx, output := range entry.Outputs {
+		if recorded.Outputs[idx] != output {
+			return false
+		}
+		if _, err := os.Stat(filepath.Join(m.dir, filepath.FromSlash(output))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// record notes that the source has been published as entry describes. Earlier
+// outputs the entry leaves out are kept as stale.
+func (m *manifest) record(relPath string, entry manifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := filepath.ToSlash(relPath)
+	if recorded, ok := m.Files[key]; ok {
+		for _, output := range slices.Concat(recorded.Stale, recorded.Outputs) {
+			if !slices.Contains(entry.Outputs, output) && !slices.Contains(entry.Stale, output) {
+				entry.Stale = append(entry.Stale, output)
+			}
+		}
+	}
+	m.Files[key] = entry
+}
+
+// save writes the manifest atomically.
+func (m *manifest) save() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(m.dir, manifestFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(m.dir, manifestFile))
+}
+
+// fileHash returns the hex SHA-256 of the file at path.
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
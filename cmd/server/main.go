@@ -8,16 +8,22 @@ import (
 	"personalwebsite/internal/config"
 	"personalwebsite/internal/portfolio"
 	"personalwebsite/internal/web"
+	"strconv"
+	"time"
 )
 
 func main() {
-	blogService := blog.NewFilesystemService("content/blog")
-	portfolioService := portfolio.NewFilesystemService(config.ResolvePortfolioRoot(), "/assets/portfolio")
+	blogService := blog.NewFilesystemService("content/blog", blog.WithPortfolioAssets(config.ResolvePortfolioRoot()))
+	portfolioService := portfolio.NewFilesystemService(config.ResolvePortfolioRoot(), "/assets/portfolio", portfolio.WithCollections(config.CollectionsRoot))
 
 	serverConfig := web.ServerConfig{
 		PortfolioAssetsPath: config.ResolvePortfolioRoot(),
 		AboutmeAssetsPath:   config.ResolveAboutmeRoot(),
 		CSSAssetsPath:       "internal/assets",
+		SessionSecret:       []byte(os.Getenv("SESSION_SECRET")),
+		OrdersPath:          ordersPath(),
+		AdminPasswordHash:   os.Getenv("ADMIN_PASSWORD_HASH"),
+		Slideshow:           slideshowConfig(),
 	}
 
 	server := web.NewServer(blogService, portfolioService, serverConfig)
@@ -33,3 +39,28 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// slideshowConfig reads SLIDESHOW_INTERVAL (e.g. "12s"), SLIDESHOW_SHUFFLE and
+// SLIDESHOW_CAPTIONS over the defaults.
+func slideshowConfig() web.SlideshowConfig {
+	cfg := web.DefaultSlideshowConfig
+	if interval, err := time.ParseDuration(os.Getenv("SLIDESHOW_INTERVAL")); err == nil {
+		cfg.Interval = interval
+	}
+	if shuffle, err := strconv.ParseBool(os.Getenv("SLIDESHOW_SHUFFLE")); err == nil {
+		cfg.Shuffle = shuffle
+	}
+	if captions, err := strconv.ParseBool(os.Getenv("SLIDESHOW_CAPTIONS")); err == nil {
+		cfg.Captions = captions
+	}
+	return cfg
+}
+
+// ordersPath is where print requests are kept, outside the content tree the
+// image build replaces.
+func ordersPath() string {
+	if path := os.Getenv("ORDERS_FILE"); path != "" {
+		return path
+	}
+	return "data/orders.jsonl"
+}
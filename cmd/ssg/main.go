@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"personalwebsite/internal/blog"
+	"personalwebsite/internal/config"
+	"personalwebsite/internal/images"
 	"personalwebsite/internal/portfolio"
+	"personalwebsite/internal/web"
 	"personalwebsite/internal/web/components"
+	"strings"
 )
 
 func fatal(err error) {
@@ -60,14 +65,176 @@ func generatePortfolio(out string, pService portfolio.Service, bService blog.Ser
 		return err
 	}
 
+	for keyword, tagged := range portfolio.IndexKeywords(categories) {
+		// Keywords become directory names, so skip any that cannot be one.
+		if strings.ContainsAny(keyword, `/\`) || strings.HasPrefix(keyword, ".") {
+			continue
+		}
+		pagePath := filepath.Join(out, "portfolio", "tags", keyword, "index.html")
+		err = renderPage(pagePath, components.TagPage(keyword, tagged, photoToBlog).Render)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := generateSlideshow(filepath.Join(out, "portfolio", "slideshow"), "Portfolio", portfolio.Playlist(categories)); err != nil {
+		return err
+	}
+
+	geotagged := portfolio.GeotaggedImages(categories)
+	err = renderPage(filepath.Join(out, "map", "index.html"), components.MapPage(geotagged, photoToBlog).Render)
+	if err != nil {
+		return err
+	}
+	if err := writeGeoJSON(filepath.Join(out, "api", "photos.geojson"), geotagged); err != nil {
+		return err
+	}
+
 	for _, cat := range categories {
-		fullCat, err := pService.GetCategory(cat.Name)
+		if err := generateAlbum(out, cat.Path, pService, categories, photoToBlog); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeGeoJSON(outputPath string, images []portfolio.Image) error {
+	content, err := json.Marshal(portfolio.GeoJSON(images))
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", outputPath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", filepath.Dir(outputPath), err)
+	}
+	return os.WriteFile(outputPath, content, 0644)
+}
+
+// generateSlideshow renders a slideshow with the default settings; static pages
+// cannot read the query overrides the server honours.
+func generateSlideshow(dir, title string, images []portfolio.Image) error {
+	cfg := web.DefaultSlideshowConfig
+	page := components.SlideshowPage(title, images, cfg.Interval.Milliseconds(), cfg.Shuffle, cfg.Captions)
+	return renderPage(filepath.Join(dir, "index.html"), page.Render)
+}
+
+func generateAlbum(out, albumPath string, pService portfolio.Service, categories []portfolio.Category, photoToBlog map[string]string) error {
+	album, err := pService.GetCategory(albumPath)
+	if err != nil {
+		return fmt.Errorf("loading album %s: %w", albumPath, err)
+	}
+	pagePath := filepath.Join(out, "portfolio", filepath.FromSlash(album.Path), "index.html")
+	err = renderPage(pagePath, components.PortfolioCategory(album, categories, photoToBlog).Render)
+	if err != nil {
+		return err
+	}
+
+	slideshowDir := filepath.Join(out, "portfolio", filepath.FromSlash(album.Path), "slideshow")
+	if err := generateSlideshow(slideshowDir, album.Name, portfolio.Playlist([]portfolio.Category{album})); err != nil {
+		return err
+	}
+
+	for _, img := range album.Images {
+		photo, prevPhoto, nextPhoto := portfolio.FindPhoto(album.Images, img.Slug())
+		photoPath := filepath.Join(out, "portfolio", filepath.FromSlash(album.Path), img.Slug(), "index.html")
+		err = renderPage(photoPath, components.PhotoPage(album, *photo, prevPhoto, nextPhoto, photoToBlog[photo.Path]).Render)
 		if err != nil {
-			return fmt.Errorf("loading category %s: %w", cat.Name, err)
+			return err
+		}
+	}
+
+	for _, child := range album.Albums {
+		if err := generateAlbum(out, child.Path, pService, categories, photoToBlog); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// staticPortfolio is the portfolio as the static site shows it. Archives are
+// streamed and print orders taken by the server, so it has neither to link to.
+type staticPortfolio struct {
+	portfolio.Service
+}
+
+func (s staticPortfolio) GetCategories() ([]portfolio.Category, error) {
+	categories, err := s.Service.GetCategories()
+	return staticCategories(categories), err
+}
+
+func (s staticPortfolio) GetCategory(name string) (portfolio.Category, error) {
+	category, err := s.Service.GetCategory(name)
+	return staticCategory(category), err
+}
+
+func (s staticPortfolio) GetCollections() ([]portfolio.Collection, error) {
+	collections, err := s.Service.GetCollections()
+	for idx := range collections {
+		collections[idx] = staticCollection(collections[idx])
+	}
+	return collections, err
+}
+
+func (s staticPortfolio) GetCollection(name string) (portfolio.Collection, error) {
+	collection, err := s.Service.GetCollection(name)
+	return staticCollection(collection), err
+}
+
+func staticCategories(categories []portfolio.Category) []portfolio.Category {
+	if categories == nil {
+		return nil
+	}
+	static := make([]portfolio.Category, len(categories))
+	for idx, category := range categories {
+		static[idx] = staticCategory(category)
+	}
+	return static
+}
+
+func staticCategory(category portfolio.Category) portfolio.Category {
+	category.Downloadable = false
+	category.Images = withoutPrints(category.Images)
+	category.CoverImage.PrintSizes = nil
+	category.Albums = staticCategories(category.Albums)
+	return category
+}
+
+func staticCollection(collection portfolio.Collection) portfolio.Collection {
+	collection.Images = withoutPrints(collection.Images)
+	collection.CoverImage.PrintSizes = nil
+	return collection
+}
+
+// withoutPrints returns a copy of images offering no print sizes.
+func withoutPrints(images []portfolio.Image) []portfolio.Image {
+	if images == nil {
+		return nil
+	}
+	static := make([]portfolio.Image, len(images))
+	for idx, img := range images {
+		img.PrintSizes = nil
+		static[idx] = img
+	}
+	return static
+}
+
+func generateCollections(out string, pService portfolio.Service, bService blog.Service) error {
+	collections, err := pService.GetCollections()
+	if err != nil {
+		return fmt.Errorf("loading collections: %w", err)
+	}
+
+	posts, err := bService.GetAllPosts()
+	if err != nil {
+		return fmt.Errorf("loading blog posts: %w", err)
+	}
 
-		pagePath := filepath.Join(out, "portfolio", cat.Name, "index.html")
-		err = renderPage(pagePath, components.PortfolioCategory(fullCat, categories, photoToBlog).Render)
+	photoToBlog := blog.BuildPhotoToBlogMap(posts)
+
+	for _, collection := range collections {
+		pagePath := filepath.Join(out, "collections", collection.Name, "index.html")
+		err = renderPage(pagePath, components.CollectionPage(collection, photoToBlog).Render)
 		if err != nil {
 			return err
 		}
@@ -100,6 +267,46 @@ func generateBlog(out string, bService blog.Service) error {
 }
 
 func copyDir(src, dst string) error {
+	return copyDirFiltered(src, dst, nil)
+}
+
+// copyPortfolio copies the published portfolio, leaving out private albums and
+// the album settings files (which hold password hashes).
+func copyPortfolio(src, dst string) error {
+	return copyDirFiltered(src, dst, func(relPath string, info os.FileInfo) (bool, error) {
+		if !info.IsDir() {
+			if info.Name() == portfolio.AlbumMetadataFile {
+				return true, nil
+			}
+			return isUnmarkedOriginal(src, relPath)
+		}
+		albumAccess, err := portfolio.LoadAccess(src, filepath.ToSlash(relPath))
+		if err == portfolio.ErrCategoryNotFound {
+			return true, nil
+		}
+		return albumAccess.IsPrivate(), err
+	})
+}
+
+// isUnmarkedOriginal reports whether relPath is the full-size original of a
+// photo in a watermarked album. Pages only link to its variants, the widest of
+// which carries the mark, so the original is left out rather than published clean.
+func isUnmarkedOriginal(root, relPath string) (bool, error) {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if (ext != ".jpg" && ext != ".jpeg" && ext != ".png") || images.IsVariantName(filepath.Base(relPath)) {
+		return false, nil
+	}
+	watermark, err := portfolio.LoadWatermark(root, filepath.ToSlash(filepath.Dir(relPath)))
+	if err != nil {
+		return false, err
+	}
+	widths := images.FullLadder.Widths
+	return len(widths) > 0 && watermark.AppliesTo(widths[len(widths)-1]), nil
+}
+
+// copyDirFiltered copies src to dst, leaving out files and directories for which
+// skip returns true.
+func copyDirFiltered(src, dst string, skip func(relPath string, info os.FileInfo) (bool, error)) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -110,6 +317,24 @@ func copyDir(src, dst string) error {
 			return err
 		}
 
+		// Dot files, like the optimizer's manifest, are bookkeeping.
+		if !info.IsDir() && strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		if skip != nil {
+			skipped, err := skip(relPath, info)
+			if err != nil {
+				return err
+			}
+			if skipped && info.IsDir() {
+				return filepath.SkipDir
+			}
+			if skipped {
+				return nil
+			}
+		}
+
 		destPath := filepath.Join(dst, relPath)
 
 		if info.IsDir() {
@@ -143,19 +368,20 @@ func main() {
 
 	portfolioRoot := "content/portfolio_optimized"
 	if _, err := os.Stat(portfolioRoot); os.IsNotExist(err) {
-		log.Fatal("optimized portfolio not found. Run 'go run cmd/optimize/main.go' first.")
+		log.Fatal("optimized portfolio not found. Run 'go run ./cmd/optimize' first.")
 	}
 
-	blogService := blog.NewFilesystemService("content/blog")
-	portfolioService := portfolio.NewFilesystemService(portfolioRoot, "/assets/portfolio")
+	blogService := blog.NewFilesystemService("content/blog", blog.WithPortfolioAssets(portfolioRoot))
+	portfolioService := staticPortfolio{portfolio.NewFilesystemService(portfolioRoot, "/assets/portfolio", portfolio.WithCollections(config.CollectionsRoot))}
 
 	fatal(generateHome(outputDir))
 	fatal(generateAbout(outputDir))
 	fatal(generatePortfolio(outputDir, portfolioService, blogService))
+	fatal(generateCollections(outputDir, portfolioService, blogService))
 	fatal(generateBlog(outputDir, blogService))
 
 	fatal(copyDir("internal/assets", filepath.Join(outputDir, "assets")))
-	fatal(copyDir("content/portfolio_optimized", filepath.Join(outputDir, "assets/portfolio")))
+	fatal(copyPortfolio("content/portfolio_optimized", filepath.Join(outputDir, "assets/portfolio")))
 	fatal(copyDir("content/aboutme_optimized", filepath.Join(outputDir, "assets/aboutme")))
 
 	fatal(os.WriteFile(filepath.Join(outputDir, "CNAME"), []byte("merlmartin.com"), 0644))
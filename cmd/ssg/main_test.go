@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"personalwebsite/internal/blog"
+	"personalwebsite/internal/portfolio"
+	"strings"
+	"testing"
+)
+
+// printsService offers prints of every photo and archives of every album, as
+// the server would.
+type printsService struct {
+	portfolio.Service
+}
+
+var printed = portfolio.Image{Path: "/assets/portfolio/Wildlife/bear", Ext: ".jpg", Album: "Wildlife", PrintSizes: []string{"8x10", "12x18"}}
+
+func (printsService) GetCategories() ([]portfolio.Category, error) {
+	return []portfolio.Category{{
+		Name: "Wildlife", Path: "Wildlife", Images: []portfolio.Image{printed}, CoverImage: printed, Downloadable: true,
+		Albums: []portfolio.Category{{Name: "Bears", Path: "Wildlife/Bears", Images: []portfolio.Image{printed}, Downloadable: true}},
+	}}, nil
+}
+
+func (s printsService) GetCategory(name string) (portfolio.Category, error) {
+	categories, _ := s.GetCategories()
+	if name == "Wildlife" {
+		return categories[0], nil
+	}
+	return categories[0].Albums[0], nil
+}
+
+func (printsService) GetCollections() ([]portfolio.Collection, error) {
+	return []portfolio.Collection{{Name: "best", Title: "Best", Images: []portfolio.Image{printed}, CoverImage: printed}}, nil
+}
+
+// writeFile writes data to the slash-separated relPath under dir.
+func writeFile(t *testing.T, dir, relPath string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyPortfolio(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for _, relPath := range []string{
+		"Wildlife/bear.jpg", "Wildlife/bear_w600.jpg", "Wildlife/bear_w1600.jpg",
+		"Alaska/denali.jpg", "Alaska/denali_w600.jpg",
+		"Clients/wedding.jpg", ".branding/mark.png", ".manifest.json",
+	} {
+		writeFile(t, src, relPath, []byte("published"))
+	}
+	writeFile(t, src, "Wildlife/album.yaml", []byte("watermark:\n  image: ../.branding/mark.png\n"))
+	writeFile(t, src, "Clients/album.yaml", []byte("private: true\npassword_hash: secret\n"))
+
+	if err := copyPortfolio(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for relPath, want := range map[string]bool{
+		"Wildlife/bear_w600.jpg":  true,
+		"Wildlife/bear_w1600.jpg": true,
+		"Alaska/denali.jpg":       true,
+		"Alaska/denali_w600.jpg":  true,
+		"Wildlife/bear.jpg":       false, // the unmarked original of a watermarked album
+		"Wildlife/album.yaml":     false,
+		"Clients/wedding.jpg":     false,
+		"Clients/album.yaml":      false,
+		".branding/mark.png":      false,
+		".manifest.json":          false,
+	} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(relPath))); (err == nil) != want {
+			t.Errorf("%s: expected published %v, got %v", relPath, want, err)
+		}
+	}
+}
+
+func TestStaticPortfolio_LinksNoServerOnlyPages(t *testing.T) {
+	out := t.TempDir()
+	pService := staticPortfolio{printsService{}}
+	if err := generateAlbum(out, "Wildlife", pService, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateCollections(out, pService, blog.NewMemoryService()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range []string{
+		"portfolio/Wildlife/index.html",
+		"portfolio/Wildlife/bear/index.html",
+		"portfolio/Wildlife/Bears/index.html",
+		"collections/best/index.html",
+	} {
+		content, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(page)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "/prints/Wildlife") || strings.Contains(string(content), "8x10") {
+			t.Errorf("%s: expected no print orders on the static site", page)
+		}
+		if strings.Contains(string(content), "/download") {
+			t.Errorf("%s: expected no archive downloads on the static site", page)
+		}
+	}
+
+	// The service's own results are left as they were.
+	if categories, _ := (printsService{}).GetCategories(); len(categories[0].Images[0].PrintSizes) == 0 {
+		t.Error("expected the wrapped service's photos to keep their print sizes")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"personalwebsite/internal/config"
 	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
 	"strings"
 	"time"
 )
@@ -15,10 +16,7 @@ func main() {
 
 	contentRoot := config.ResolvePortfolioRoot()
 
-	cacheRoot := os.Getenv("CACHE_DIR")
-	if cacheRoot == "" {
-		cacheRoot = filepath.Join(os.TempDir(), "personalwebsite_cache")
-	}
+	cacheRoot := config.ResolveCacheRoot()
 
 	if _, err := os.Stat(contentRoot); os.IsNotExist(err) {
 		fmt.Printf("Error: %s not found. Please run from project root.\n", contentRoot)
@@ -28,12 +26,21 @@ func main() {
 	fmt.Printf("Portfolio root: %s\n", contentRoot)
 	fmt.Printf("Cache root: %s\n", cacheRoot)
 
+	cache, err := images.OpenCache(cacheRoot, config.CacheMaxBytes())
+	if err != nil {
+		fmt.Printf("Error indexing cache: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create resizer
-	resizer := images.NewResizer(contentRoot, cacheRoot)
+	resizer := images.NewResizer(contentRoot, cacheRoot,
+		images.WithWatermarks(portfolio.Watermarks(contentRoot)),
+		images.WithFocalPoints(portfolio.FocalPoints(contentRoot)),
+		images.WithCache(cache))
 
 	// Find all images
 	var imagesToProcess []string
-	err := filepath.Walk(contentRoot, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(contentRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -41,6 +48,11 @@ func main() {
 			return nil
 		}
 
+		// Prebuilt variants are outputs, not sources to render from.
+		if images.IsVariantName(info.Name()) {
+			return nil
+		}
+
 		ext := strings.ToLower(filepath.Ext(path))
 		if ext == ".jpg" || ext == ".jpeg" || ext == ".png" {
 			// We need path relative to contentRoot
@@ -60,14 +72,12 @@ func main() {
 
 	fmt.Printf("Found %d images to process.\n", len(imagesToProcess))
 
-	widths := []int{600, 1600}
-
 	for _, relPath := range imagesToProcess {
 		fmt.Printf("Processing %s... ", relPath)
-		for _, w := range widths {
-			_, err := resizer.Resize(relPath, w)
+		for _, spec := range images.DefaultPipeline.Variants {
+			_, err := resizer.Render(relPath, spec)
 			if err != nil {
-				fmt.Printf("\nError resizing %s to %d: %v\n", relPath, w, err)
+				fmt.Printf("\nError rendering %s%s: %v\n", relPath, spec.Suffix(), err)
 			}
 		}
 		fmt.Println("Done")
@@ -7,10 +7,8 @@ require (
 	github.com/adrg/frontmatter v0.2.0
 	github.com/disintegration/imaging v1.6.2
 	github.com/yuin/goldmark v1.7.16
+	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8
+	gopkg.in/yaml.v2 v2.3.0
 )
 
-require (
-	github.com/BurntSushi/toml v0.3.1 // indirect
-	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8 // indirect
-	gopkg.in/yaml.v2 v2.3.0 // indirect
-)
+require github.com/BurntSushi/toml v0.3.1 // indirect
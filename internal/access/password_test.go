@@ -0,0 +1,52 @@
+package access
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2SHA256_KnownVectors(t *testing.T) {
+	tests := []struct {
+		iterations int
+		expected   string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), tt.iterations))
+		if got != tt.expected {
+			t.Errorf("iterations %d: expected %s, got %s", tt.iterations, tt.expected, got)
+		}
+	}
+}
+
+func TestHashPassword_RoundTrip(t *testing.T) {
+	hash, err := HashPassword("client-proofs")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$") || strings.Contains(hash, "client-proofs") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	if !CheckPassword(hash, "client-proofs") {
+		t.Error("expected the password to match its hash")
+	}
+	if CheckPassword(hash, "wrong") {
+		t.Error("expected a wrong password not to match")
+	}
+
+	other, _ := HashPassword("client-proofs")
+	if other == hash {
+		t.Error("expected hashes to be salted")
+	}
+}
+
+func TestCheckPassword_RejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{"", "client-proofs", "md5$1$c2FsdA$aGFzaA", "pbkdf2-sha256$0$c2FsdA$aGFzaA", "pbkdf2-sha256$1$c2FsdA$aGFzaA"} {
+		if CheckPassword(hash, "client-proofs") {
+			t.Errorf("expected %q never to match", hash)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package access
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionCookie holds the private albums a visitor has unlocked.
+const SessionCookie = "portfolio_session"
+
+// SessionTTL is how long an album unlocked with its password stays unlocked.
+const SessionTTL = 7 * 24 * time.Hour
+
+// Sessions issues and verifies HMAC-signed session cookies.
+type Sessions struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSessions signs cookies with secret. An empty secret is replaced by a random
+// one, so sessions then only last until the process restarts.
+func NewSessions(secret []byte) *Sessions {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	return &Sessions{secret: secret, now: time.Now}
+}
+
+// Allowed reports whether the request's session has unlocked album.
+func (s *Sessions) Allowed(r *http.Request, album string) bool {
+	_, ok := s.albums(r)[album]
+	return ok
+}
+
+// Grant adds album to the request's session until expires and writes the
+// renewed cookie. Albums already unlocked keep their own expiry.
+func (s *Sessions) Grant(w http.ResponseWriter, r *http.Request, album string, expires time.Time) {
+	albums := s.albums(r)
+	if expires.After(albums[album]) {
+		albums[album] = expires
+	}
+
+	var lines []string
+	var cookieExpires time.Time
+	for name, albumExpires := range albums {
+		lines = append(lines, strconv.FormatInt(albumExpires.Unix(), 10)+"\t"+name)
+		if albumExpires.After(cookieExpires) {
+			cookieExpires = albumExpires
+		}
+	}
+	payload := strings.Join(lines, "\n")
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + sign(s.secret, payload),
+		Path:     "/",
+		Expires:  cookieExpires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// albums returns the unexpired albums in a valid session cookie with their expiry.
+func (s *Sessions) albums(r *http.Request) map[string]time.Time {
+	albums := make(map[string]time.Time)
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return albums
+	}
+	encoded, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return albums
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return albums
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(signature), []byte(sign(s.secret, payload))) {
+		return albums
+	}
+
+	for _, line := range strings.Split(payload, "\n") {
+		expiry, album, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		expires, err := strconv.ParseInt(expiry, 10, 64)
+		if err != nil || s.now().Unix() > expires {
+			continue
+		}
+		albums[album] = time.Unix(expires, 0)
+	}
+	return albums
+}
+
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
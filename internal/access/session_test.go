@@ -0,0 +1,82 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requestWithCookies returns a request carrying the cookies set on recorder.
+func requestWithCookies(recorder *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/Clients", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestSessions_GrantAllowsOnlyUnlockedAlbums(t *testing.T) {
+	sessions := NewSessions([]byte("secret"))
+
+	recorder := httptest.NewRecorder()
+	sessions.Grant(recorder, httptest.NewRequest(http.MethodPost, "/portfolio/Clients", nil), "Clients", time.Now().Add(SessionTTL))
+	req := requestWithCookies(recorder)
+
+	if !sessions.Allowed(req, "Clients") {
+		t.Error("expected the granted album to be allowed")
+	}
+	if sessions.Allowed(req, "Clients/Smith") {
+		t.Error("expected other albums to stay locked")
+	}
+
+	// Granting a second album keeps the first.
+	recorder = httptest.NewRecorder()
+	sessions.Grant(recorder, req, "Proofs", time.Now().Add(time.Hour))
+	req = requestWithCookies(recorder)
+	if !sessions.Allowed(req, "Clients") || !sessions.Allowed(req, "Proofs") {
+		t.Error("expected both albums to be allowed")
+	}
+}
+
+func TestSessions_RejectsTamperedForeignAndExpiredCookies(t *testing.T) {
+	sessions := NewSessions([]byte("secret"))
+	recorder := httptest.NewRecorder()
+	sessions.Grant(recorder, httptest.NewRequest(http.MethodPost, "/", nil), "Clients", time.Now().Add(SessionTTL))
+	cookie := recorder.Result().Cookies()[0]
+
+	tampered := httptest.NewRequest(http.MethodGet, "/", nil)
+	encoded, signature, _ := strings.Cut(cookie.Value, ".")
+	tampered.AddCookie(&http.Cookie{Name: SessionCookie, Value: encoded + "x." + signature})
+	if sessions.Allowed(tampered, "Clients") {
+		t.Error("expected a tampered cookie to be rejected")
+	}
+
+	if NewSessions([]byte("other")).Allowed(requestWithCookies(recorder), "Clients") {
+		t.Error("expected a cookie signed with another secret to be rejected")
+	}
+
+	sessions.now = func() time.Time { return time.Now().Add(SessionTTL + time.Hour) }
+	if sessions.Allowed(requestWithCookies(recorder), "Clients") {
+		t.Error("expected an expired cookie to be rejected")
+	}
+}
+
+func TestSessions_AlbumsExpireIndependently(t *testing.T) {
+	sessions := NewSessions([]byte("secret"))
+	recorder := httptest.NewRecorder()
+	sessions.Grant(recorder, httptest.NewRequest(http.MethodPost, "/", nil), "Clients", time.Now().Add(SessionTTL))
+	req := requestWithCookies(recorder)
+	recorder = httptest.NewRecorder()
+	sessions.Grant(recorder, req, "Proofs", time.Now().Add(time.Hour))
+	req = requestWithCookies(recorder)
+
+	sessions.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if !sessions.Allowed(req, "Clients") {
+		t.Error("expected Clients to stay unlocked")
+	}
+	if sessions.Allowed(req, "Proofs") {
+		t.Error("expected the short-lived Proofs grant to expire")
+	}
+}
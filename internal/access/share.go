@@ -0,0 +1,61 @@
+package access
+
+import (
+	"crypto/hmac"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameters of a share link.
+const (
+	ShareAlbumParam     = "album"
+	ShareExpiresParam   = "expires"
+	ShareSignatureParam = "sig"
+)
+
+// ShareLinks signs and verifies time-limited links to private albums.
+type ShareLinks struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewShareLinks signs links with secret, which must match between the server
+// and whoever mints the links.
+func NewShareLinks(secret []byte) *ShareLinks {
+	return &ShareLinks{secret: secret, now: time.Now}
+}
+
+// Sign returns the query parameters that unlock album until expires.
+func (l *ShareLinks) Sign(album string, expires time.Time) url.Values {
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return url.Values{
+		ShareAlbumParam:     {album},
+		ShareExpiresParam:   {expiry},
+		ShareSignatureParam: {sign(l.secret, sharePayload(album, expiry))},
+	}
+}
+
+// Verify returns the album and expiry of a correctly signed, unexpired share link.
+func (l *ShareLinks) Verify(query url.Values) (string, time.Time, bool) {
+	if len(l.secret) == 0 {
+		return "", time.Time{}, false
+	}
+	album, expiry, signature := query.Get(ShareAlbumParam), query.Get(ShareExpiresParam), query.Get(ShareSignatureParam)
+	if album == "" || signature == "" {
+		return "", time.Time{}, false
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign(l.secret, sharePayload(album, expiry)))) {
+		return "", time.Time{}, false
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || l.now().Unix() > expires {
+		return "", time.Time{}, false
+	}
+	return album, time.Unix(expires, 0), true
+}
+
+// sharePayload is prefixed so a share signature can never pass as a session cookie.
+func sharePayload(album, expiry string) string {
+	return "share\n" + album + "\n" + expiry
+}
@@ -0,0 +1,51 @@
+package access
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestShareLinks_SignAndVerify(t *testing.T) {
+	links := NewShareLinks([]byte("secret"))
+	expires := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+
+	query := links.Sign("Clients/Smith", expires)
+	album, verifiedExpires, ok := links.Verify(query)
+	if !ok || album != "Clients/Smith" || !verifiedExpires.Equal(expires) {
+		t.Fatalf("expected a valid link for Clients/Smith until %v, got %q %v %v", expires, album, verifiedExpires, ok)
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		links *ShareLinks
+	}{
+		{"other album", withParam(query, ShareAlbumParam, "Clients"), links},
+		{"extended expiry", withParam(query, ShareExpiresParam, "9999999999"), links},
+		{"other secret", query, NewShareLinks([]byte("other"))},
+		{"no secret", query, NewShareLinks(nil)},
+		{"missing signature", withParam(query, ShareSignatureParam, ""), links},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := tt.links.Verify(tt.query); ok {
+				t.Error("expected the link to be rejected")
+			}
+		})
+	}
+
+	links.now = func() time.Time { return expires.Add(time.Second) }
+	if _, _, ok := links.Verify(query); ok {
+		t.Error("expected an expired link to be rejected")
+	}
+}
+
+func withParam(query url.Values, key, value string) url.Values {
+	changed := url.Values{}
+	for k, v := range query {
+		changed[k] = v
+	}
+	changed.Set(key, value)
+	return changed
+}
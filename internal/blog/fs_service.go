@@ -13,14 +13,32 @@ import (
 )
 
 type filesystemService struct {
-	dir string
+	dir       string
+	assetsDir string
+	markdown  goldmark.Markdown
 }
 
-func NewFilesystemService(dir string) Service {
-	return &filesystemService{dir: dir}
+// Option configures optional parts of the filesystem service.
+type Option func(*filesystemService)
+
+// WithPortfolioAssets gives posts' portfolio photos a srcset over the variants
+// published in dir, the directory served at /assets/portfolio/.
+func WithPortfolioAssets(dir string) Option {
+	return func(s *filesystemService) {
+		s.assetsDir = dir
+	}
+}
+
+func NewFilesystemService(dir string, opts ...Option) Service {
+	s := &filesystemService{dir: dir}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.markdown = newMarkdown(s.assetsDir)
+	return s
 }
 
-func parsePost(filePath string) (Post, error) {
+func parsePost(markdown goldmark.Markdown, filePath string) (Post, error) {
 	fileContent, readErr := os.ReadFile(filePath)
 	if readErr != nil {
 		return Post{}, readErr
@@ -39,7 +57,7 @@ func parsePost(filePath string) (Post, error) {
 	}
 
 	var buf bytes.Buffer
-	if convertErr := goldmark.Convert(rest, &buf); convertErr != nil {
+	if convertErr := markdown.Convert(rest, &buf); convertErr != nil {
 		return Post{}, convertErr
 	}
 
@@ -74,7 +92,7 @@ func (svc *filesystemService) GetAllPosts() ([]Post, error) {
 		}
 
 		entryPath := filepath.Join(svc.dir, entry.Name())
-		post, parseErr := parsePost(entryPath)
+		post, parseErr := parsePost(svc.markdown, entryPath)
 		if parseErr != nil {
 			return nil, parseErr
 		}
@@ -96,7 +114,7 @@ func (svc *filesystemService) GetPost(slug string) (Post, error) {
 		return Post{}, ErrPostNotFound
 	}
 
-	post, parseErr := parsePost(postPath)
+	post, parseErr := parsePost(svc.markdown, postPath)
 	if parseErr != nil {
 		return Post{}, parseErr
 	}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"personalwebsite/internal/blog"
+	"personalwebsite/internal/images"
 )
 
 func writeMarkdownFile(t *testing.T, dir, slug, title, date, summary, body string) {
@@ -263,3 +264,34 @@ summary: "This will fail to parse."
 		t.Errorf("Expected Slug 'good-post', got '%s'", post.Slug)
 	}
 }
+
+func TestFilesystemService_ResponsivePortfolioImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeMarkdownFile(t, tmpDir, "river", "River", "2018-06-23", "Nets.",
+		"![Fishing nets](/assets/portfolio/Alaska/DSC06091.jpg)\n\n![Unpublished](/assets/portfolio/Alaska/DSC06092.jpg)\n\n![Elsewhere](https://example.com/photo.jpg)\n")
+
+	assetsDir := t.TempDir()
+	os.MkdirAll(filepath.Join(assetsDir, "Alaska"), 0755)
+	for _, name := range []string{"DSC06091.jpg", "DSC06091_w600.jpg", "DSC06091_w1600.jpg"} {
+		os.WriteFile(filepath.Join(assetsDir, "Alaska", name), []byte(name), 0644)
+	}
+	// Variant URLs carry the fingerprint of the file published at them.
+	v600 := "?v=" + images.FingerprintData([]byte("DSC06091_w600.jpg"))
+	v1600 := "?v=" + images.FingerprintData([]byte("DSC06091_w1600.jpg"))
+
+	post, err := blog.NewFilesystemService(tmpDir, blog.WithPortfolioAssets(assetsDir)).GetPost("river")
+	if err != nil {
+		t.Fatalf("GetPost returned error: %v", err)
+	}
+
+	want := `<img src="/assets/portfolio/Alaska/DSC06091_w1600.jpg` + v1600 + `" alt="Fishing nets" srcset="/assets/portfolio/Alaska/DSC06091_w600.jpg` + v600 + ` 600w, /assets/portfolio/Alaska/DSC06091_w1600.jpg` + v1600 + ` 1600w" sizes="(min-width: 768px) 768px, 100vw" loading="lazy">`
+	if !strings.Contains(post.Content, want) {
+		t.Errorf("expected a responsive portfolio photo, got %s", post.Content)
+	}
+	if !strings.Contains(post.Content, `<img src="/assets/portfolio/Alaska/DSC06092.jpg" alt="Unpublished">`) {
+		t.Errorf("expected photos without published variants to be left alone, got %s", post.Content)
+	}
+	if !strings.Contains(post.Content, `<img src="https://example.com/photo.jpg" alt="Elsewhere">`) {
+		t.Errorf("expected other images to be left alone, got %s", post.Content)
+	}
+}
@@ -0,0 +1,103 @@
+package blog
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// portfolioAssetsPrefix is the URL prefix of published portfolio photos.
+const portfolioAssetsPrefix = "/assets/portfolio/"
+
+// newMarkdown returns the renderer for posts, which makes the portfolio photos
+// published in assetsDir responsive. With no assetsDir they are left as written.
+func newMarkdown(assetsDir string) goldmark.Markdown {
+	if assetsDir == "" {
+		return goldmark.New()
+	}
+	return goldmark.New(goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(responsiveImages{assetsDir: assetsDir}, 100)),
+	))
+}
+
+// responsiveImages gives Markdown images of portfolio photos a srcset over the
+// images.FullLadder variants published in assetsDir, so readers download the
+// width their screen needs.
+type responsiveImages struct {
+	assetsDir string
+}
+
+func (t responsiveImages) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := node.(*ast.Image)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		dest := string(img.Destination)
+		ext := strings.ToLower(path.Ext(dest))
+		if !strings.HasPrefix(dest, portfolioAssetsPrefix) || strings.Contains(dest, "?") || images.IsVariantName(path.Base(dest)) {
+			return ast.WalkContinue, nil
+		}
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			return ast.WalkContinue, nil
+		}
+
+		ladder := t.published(strings.TrimSuffix(dest, path.Ext(dest)), path.Ext(dest))
+		if len(ladder.Widths) == 0 {
+			return ast.WalkContinue, nil
+		}
+		view := ladder.Responsive(strings.TrimSuffix(dest, path.Ext(dest)), path.Ext(dest), images.ProseLayout, nil)
+		img.Destination = []byte(view.Src)
+		img.SetAttributeString("srcset", []byte(view.SrcSet))
+		img.SetAttributeString("sizes", []byte(view.Sizes))
+		img.SetAttributeString("loading", []byte("lazy"))
+		return ast.WalkContinue, nil
+	})
+}
+
+// published returns the part of images.FullLadder whose variants of the photo
+// at the URL base+ext exist in assetsDir, fingerprinted by their content.
+func (t responsiveImages) published(base, ext string) images.Ladder {
+	var widths []int
+	for _, width := range images.FullLadder.Widths {
+		if path, ok := t.assetPath(images.FullLadder.Variant(base, ext, width)); ok {
+			if _, err := os.Stat(path); err == nil {
+				widths = append(widths, width)
+			}
+		}
+	}
+	return images.FullLadder.Only(widths).Fingerprinted(t.fingerprint)
+}
+
+// assetPath returns where in assetsDir the portfolio photo at a URL is
+// published, if the URL is one.
+func (t responsiveImages) assetPath(dest string) (string, bool) {
+	relPath, err := url.PathUnescape(strings.TrimPrefix(dest, portfolioAssetsPrefix))
+	if err != nil || !filepath.IsLocal(filepath.FromSlash(relPath)) {
+		return "", false
+	}
+	return filepath.Join(t.assetsDir, filepath.FromSlash(relPath)), true
+}
+
+// fingerprint returns the images.Fingerprint of the variant at a URL, or ""
+// when it cannot be read.
+func (t responsiveImages) fingerprint(dest string) string {
+	path, ok := t.assetPath(dest)
+	if !ok {
+		return ""
+	}
+	hash, err := images.Fingerprint(path)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
@@ -62,7 +62,9 @@ func (post Post) LinkedCategory() string {
 	if len(parts) < 5 {
 		return ""
 	}
-	return parts[3]
+	// Everything between "/assets/portfolio/" and the file name, so photos in
+	// nested albums link to the album rather than its top-level category.
+	return strings.Join(parts[3:len(parts)-1], "/")
 }
 
 func FindNeighbors(posts []Post, slug string) (*Post, *Post) {
@@ -43,6 +43,17 @@ func TestLinkedCategory_ReturnsWildlifeFromWildlifePhotos(t *testing.T) {
 	}
 }
 
+func TestLinkedCategory_ReturnsNestedAlbumPath(t *testing.T) {
+	post := Post{
+		LinkedPhotos: []string{"/assets/portfolio/Alaska/2018/DSC05907.jpg"},
+	}
+
+	category := post.LinkedCategory()
+	if category != "Alaska/2018" {
+		t.Errorf("Expected 'Alaska/2018', got '%s'", category)
+	}
+}
+
 func TestLinkedCategory_ReturnsEmptyWhenNoPhotos(t *testing.T) {
 	post := Post{
 		LinkedPhotos: []string{},
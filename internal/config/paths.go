@@ -1,6 +1,13 @@
 package config
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// CollectionsRoot holds the YAML manifests for curated cross-category collections.
+const CollectionsRoot = "content/collections"
 
 func ResolvePortfolioRoot() string {
 	optimized := "content/portfolio_optimized"
@@ -17,3 +24,32 @@ func ResolveAboutmeRoot() string {
 	}
 	return "content/aboutme"
 }
+
+// ResolveCacheRoot is where resized variants are cached: CACHE_DIR, or a
+// directory under the system temp dir.
+func ResolveCacheRoot() string {
+	if cacheRoot := os.Getenv("CACHE_DIR"); cacheRoot != "" {
+		return cacheRoot
+	}
+	return filepath.Join(os.TempDir(), "personalwebsite_cache")
+}
+
+// defaultCacheMaxMB is the cache budget when CACHE_MAX_MB is unset.
+const defaultCacheMaxMB = 2048
+
+// CacheMaxBytes is the size the cache is kept under: CACHE_MAX_MB megabytes,
+// 2 GB by default. Zero turns eviction off.
+func CacheMaxBytes() int64 {
+	megabytes, err := strconv.ParseInt(os.Getenv("CACHE_MAX_MB"), 10, 64)
+	if err != nil || megabytes < 0 {
+		megabytes = defaultCacheMaxMB
+	}
+	return megabytes << 20
+}
+
+// ServerURL is SERVER_URL, where the server is deployed, or "" when unset.
+// Links to pages only the server has, such as private albums, must point there
+// rather than at the static site.
+func ServerURL() string {
+	return os.Getenv("SERVER_URL")
+}
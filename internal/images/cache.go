@@ -0,0 +1,239 @@
+package images
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheIndexFile is where a Cache keeps the access times of its files, in the
+// cache root. Dot files, like the index and temp files, are never evicted.
+const CacheIndexFile = ".index.json"
+
+// cacheIndexSaveInterval bounds how often reads rewrite the index. Access times
+// lost in a crash only make those files look as old as when they were written.
+const cacheIndexSaveInterval = time.Minute
+
+// Cache keeps a directory of generated variants within a byte budget by
+// removing the least recently used files.
+type Cache struct {
+	root     string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry // by path relative to root
+	total   int64
+	saved   time.Time
+}
+
+type cacheEntry struct {
+	Size     int64     `json:"size"`
+	Accessed time.Time `json:"accessed"`
+}
+
+// CacheUsage summarises what a cache holds.
+type CacheUsage struct {
+	Files    int
+	Bytes    int64
+	MaxBytes int64
+	// Oldest is the access time of the least recently used file.
+	Oldest time.Time
+}
+
+// OpenCache indexes the files under root, taking access times from the saved
+// index and falling back to modification times, then evicts down to maxBytes.
+// A maxBytes of zero or less means no budget.
+func OpenCache(root string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{root: root, maxBytes: maxBytes, entries: map[string]*cacheEntry{}}
+
+	saved := map[string]*cacheEntry{}
+	if data, err := os.ReadFile(filepath.Join(root, CacheIndexFile)); err == nil {
+		// A damaged index only costs the access times it held.
+		json.Unmarshal(data, &saved)
+	}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		accessed := info.ModTime()
+		if previous, ok := saved[relPath]; ok && previous.Size == info.Size() && previous.Accessed.After(accessed) {
+			accessed = previous.Accessed
+		}
+		c.entries[relPath] = &cacheEntry{Size: info.Size(), Accessed: accessed}
+		c.total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	_, _, err = c.evictLocked(maxBytes, "")
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Save()
+}
+
+// Touch records a read of the cached file at path.
+func (c *Cache) Touch(path string) {
+	relPath, ok := c.rel(path)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[relPath]
+	if ok {
+		entry.Accessed = time.Now()
+	}
+	due := time.Since(c.saved) > cacheIndexSaveInterval
+	c.mu.Unlock()
+
+	if ok && due {
+		c.Save()
+	}
+}
+
+// Add records the file just written at path, then evicts the least recently
+// used other files until the cache is back within its budget.
+func (c *Cache) Add(path string) error {
+	relPath, ok := c.rel(path)
+	if !ok {
+		return errors.New("cache: " + path + " is outside " + c.root)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if previous, ok := c.entries[relPath]; ok {
+		c.total -= previous.Size
+	}
+	c.entries[relPath] = &cacheEntry{Size: info.Size(), Accessed: time.Now()}
+	c.total += info.Size()
+	removed, _, err := c.evictLocked(c.maxBytes, relPath)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		return c.Save()
+	}
+	return nil
+}
+
+// Prune evicts the least recently used files until the cache holds at most
+// maxBytes, reporting how many files it removed and the bytes they took.
+func (c *Cache) Prune(maxBytes int64) (removed int, freed int64, err error) {
+	c.mu.Lock()
+	removed, freed, err = c.evictLocked(maxBytes, "")
+	c.mu.Unlock()
+	if saveErr := c.Save(); err == nil {
+		err = saveErr
+	}
+	return removed, freed, err
+}
+
+// Usage reports what the cache holds.
+func (c *Cache) Usage() CacheUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usage := CacheUsage{Files: len(c.entries), Bytes: c.total, MaxBytes: c.maxBytes}
+	for _, entry := range c.entries {
+		if usage.Oldest.IsZero() || entry.Accessed.Before(usage.Oldest) {
+			usage.Oldest = entry.Accessed
+		}
+	}
+	return usage
+}
+
+// Save writes the index so access times survive restarts.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.saved = time.Now()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.root, CacheIndexFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.root, CacheIndexFile))
+}
+
+// evictLocked removes files, least recently used first and never keep, until
+// the cache holds at most maxBytes. The caller holds c.mu.
+func (c *Cache) evictLocked(maxBytes int64, keep string) (removed int, freed int64, err error) {
+	if maxBytes <= 0 || c.total <= maxBytes {
+		return 0, 0, nil
+	}
+
+	paths := make([]string, 0, len(c.entries))
+	for relPath := range c.entries {
+		if relPath != keep {
+			paths = append(paths, relPath)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return c.entries[paths[i]].Accessed.Before(c.entries[paths[j]].Accessed)
+	})
+
+	for _, relPath := range paths {
+		if c.total <= maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.root, relPath)); err != nil && !os.IsNotExist(err) {
+			return removed, freed, err
+		}
+		size := c.entries[relPath].Size
+		delete(c.entries, relPath)
+		c.total -= size
+		removed++
+		freed += size
+	}
+	return removed, freed, nil
+}
+
+// rel returns path relative to the cache root, if it lies within it.
+func (c *Cache) rel(path string) (string, bool) {
+	relPath, err := filepath.Rel(c.root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return "", false
+	}
+	return relPath, true
+}
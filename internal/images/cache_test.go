@@ -0,0 +1,151 @@
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCacheFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	root := t.TempDir()
+	cache, err := OpenCache(root, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := filepath.Join(root, "Alaska", "first_w600.jpg")
+	second := filepath.Join(root, "Alaska", "second_w600.jpg")
+	third := filepath.Join(root, "third_w600.jpg")
+	for _, path := range []string{first, second} {
+		writeCacheFile(t, path, 100)
+		if err := cache.Add(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.Touch(first)
+
+	writeCacheFile(t, third, 100)
+	if err := cache.Add(third); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists(first) || exists(second) || !exists(third) {
+		t.Errorf("expected only the least recently used file to be evicted; first %v, second %v, third %v", exists(first), exists(second), exists(third))
+	}
+	if usage := cache.Usage(); usage.Files != 2 || usage.Bytes != 200 {
+		t.Errorf("expected 2 files of 200 bytes; got %+v", usage)
+	}
+}
+
+func TestCache_KeepsFileJustAdded(t *testing.T) {
+	root := t.TempDir()
+	cache, err := OpenCache(root, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "large_w1600.jpg")
+	writeCacheFile(t, path, 100)
+	if err := cache.Add(path); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(path) {
+		t.Error("expected a file larger than the budget to survive until it has been served")
+	}
+}
+
+func TestOpenCache_RebuildsIndex(t *testing.T) {
+	root := t.TempDir()
+	old := filepath.Join(root, "old_w600.jpg")
+	recent := filepath.Join(root, "recent_w600.jpg")
+	read := filepath.Join(root, "read_w600.jpg")
+	writeCacheFile(t, old, 100)
+	writeCacheFile(t, recent, 100)
+	writeCacheFile(t, read, 100)
+	writeCacheFile(t, filepath.Join(root, ".recent_w600.jpg.123.tmp"), 100)
+
+	now := time.Now()
+	os.Chtimes(old, now, now.Add(-2*time.Hour))
+	os.Chtimes(recent, now, now.Add(-time.Hour))
+	os.Chtimes(read, now, now.Add(-3*time.Hour))
+
+	// The oldest file was read since it was written, according to the index.
+	cache, err := OpenCache(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := cache.Usage(); usage.Files != 3 || usage.Bytes != 300 {
+		t.Errorf("expected the scan to find 3 files of 300 bytes, ignoring temp files; got %+v", usage)
+	}
+	cache.Touch(read)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenCache(root, 250); err != nil {
+		t.Fatal(err)
+	}
+	if exists(old) || !exists(recent) || !exists(read) {
+		t.Errorf("expected startup to evict by recorded access time; old %v, recent %v, read %v", exists(old), exists(recent), exists(read))
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a_w600.jpg", "b_w600.jpg", "c_w600.jpg"} {
+		writeCacheFile(t, filepath.Join(root, name), 100)
+	}
+	cache, err := OpenCache(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := cache.Prune(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || freed != 200 {
+		t.Errorf("expected 2 files and 200 bytes removed; got %d, %d", removed, freed)
+	}
+	if usage := cache.Usage(); usage.Files != 1 || usage.Bytes != 100 {
+		t.Errorf("expected 1 file of 100 bytes left; got %+v", usage)
+	}
+}
+
+func TestResizer_WithCache(t *testing.T) {
+	contentRoot := t.TempDir()
+	cacheRoot := t.TempDir()
+	createDummyImage(t, filepath.Join(contentRoot, "test_image.png"), 100, 100)
+	cache, err := OpenCache(cacheRoot, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resizer := NewResizer(contentRoot, cacheRoot, WithCache(cache))
+
+	small, err := resizer.Resize("test_image.png", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := resizer.Resize("test_image.png", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists(small) || !exists(large) {
+		t.Errorf("expected the resizer to keep the cache within budget; small %v, large %v", exists(small), exists(large))
+	}
+}
@@ -0,0 +1,48 @@
+package images
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Open decodes the image at path and turns it upright according to its EXIF
+// orientation, so portraits shot with the camera rotated are not sideways.
+// Colours in an embedded matrix-based ICC profile, such as Adobe RGB, are
+// converted to sRGB so that they do not look washed out once the profile is
+// gone; ProfileSegments returns the profiles that cannot be converted.
+func Open(path string) (image.Image, error) {
+	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+	if profile := readColorProfile(path); profile.convertible() && !profile.isSRGB() {
+		return profile.toSRGB(img), nil
+	}
+	return img, nil
+}
+
+// uprightSegments returns segments with the EXIF orientation, if any, set to
+// normal, for images whose pixels have been turned upright. Left alone, viewers
+// would rotate them a second time.
+func uprightSegments(segments []Segment) []Segment {
+	upright := make([]Segment, len(segments))
+	for idx, segment := range segments {
+		upright[idx] = segment
+		if !segment.IsExif() {
+			continue
+		}
+		data := append([]byte{}, segment.Data...)
+		reader, ifd0Offset, ok := newTIFFReader(data[len(exifHeader):])
+		if !ok {
+			continue
+		}
+		// Inline values alias data, so the orientation is rewritten in place.
+		entry := reader.readIFD(ifd0Offset)[tagOrientation]
+		if entry.kind == 3 && len(entry.valueRaw) == 2 {
+			reader.order.PutUint16(entry.valueRaw, 1)
+			upright[idx].Data = data
+		}
+	}
+	return upright
+}
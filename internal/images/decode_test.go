@@ -0,0 +1,159 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// uprightScene is a landscape image with only its top-left quarter red, so
+// any rotation or flip of it is told apart by its shape and that corner.
+func uprightScene() *image.NRGBA {
+	img := imaging.New(64, 32, color.NRGBA{0, 0, 255, 255})
+	return imaging.Paste(img, imaging.New(32, 16, color.NRGBA{255, 0, 0, 255}), image.Pt(0, 0))
+}
+
+// storedAs returns the pixels a camera stores for img under an EXIF
+// orientation: the inverse of the transform viewers apply to show it upright.
+func storedAs(img image.Image, orientation uint16) *image.NRGBA {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate90(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate270(img)
+	}
+	return imaging.Clone(img)
+}
+
+// orientationExif builds an EXIF segment holding only an orientation.
+func orientationExif(orientation uint16) Segment {
+	return Segment{Marker: 0xE1, Data: buildExif([]testTIFFEntry{shortEntry(tagOrientation, orientation)}, nil)}
+}
+
+// writeOrientedJPEG writes the upright scene as a camera would under the
+// given orientation.
+func writeOrientedJPEG(t *testing.T, path string, orientation uint16) {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, storedAs(uprightScene(), orientation), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := InsertSegments(&out, encoded.Bytes(), []Segment{orientationExif(orientation)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// isUpright reports whether img is the scene the right way up.
+func isUpright(img image.Image) bool {
+	bounds := img.Bounds()
+	if bounds.Dx() <= bounds.Dy() {
+		return false
+	}
+	red := func(x, y int) bool {
+		r, _, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return r > b
+	}
+	w, h := bounds.Dx(), bounds.Dy()
+	return red(w/8, h/8) && !red(w*7/8, h/8) && !red(w/8, h*7/8) && !red(w*7/8, h*7/8)
+}
+
+// readOrientation returns the EXIF orientation of the JPEG at path, or 0.
+func readOrientation(t *testing.T, path string) uint16 {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	segments, err := ReadSegments(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, segment := range segments {
+		if !segment.IsExif() {
+			continue
+		}
+		reader, offset, ok := newTIFFReader(segment.Data[len(exifHeader):])
+		if !ok {
+			continue
+		}
+		if value, ok := reader.uint32Value(reader.readIFD(offset)[tagOrientation]); ok {
+			return uint16(value)
+		}
+	}
+	return 0
+}
+
+func TestResizer_Resize_AppliesOrientation(t *testing.T) {
+	contentRoot := t.TempDir()
+	cacheRoot := t.TempDir()
+	resizer := NewResizer(contentRoot, cacheRoot)
+
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		relPath := fmt.Sprintf("orientation_%d.jpg", orientation)
+		writeOrientedJPEG(t, filepath.Join(contentRoot, relPath), orientation)
+
+		cachedPath, err := resizer.Resize(relPath, 32)
+		if err != nil {
+			t.Fatalf("orientation %d: Resize failed: %v", orientation, err)
+		}
+		if img := openImage(t, cachedPath); !isUpright(img) || img.Bounds().Dx() != 32 {
+			t.Errorf("orientation %d: expected an upright 32px variant, got %v", orientation, img.Bounds().Size())
+		}
+	}
+}
+
+func TestWriteJPEG_ResetsOrientation(t *testing.T) {
+	dir := t.TempDir()
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		source := filepath.Join(dir, fmt.Sprintf("orientation_%d.jpg", orientation))
+		writeOrientedJPEG(t, source, orientation)
+		img, err := Open(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("orientation_%d_w600.jpg", orientation))
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = WriteJPEG(file, img, 85, MetadataPolicy{}.FilterSegments([]Segment{orientationExif(orientation)}))
+		file.Close()
+		if err != nil {
+			t.Fatalf("WriteJPEG failed: %v", err)
+		}
+
+		if got := readOrientation(t, path); got != 1 {
+			t.Errorf("orientation %d: expected the orientation to be reset to 1, got %d", orientation, got)
+		}
+		published, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isUpright(published) {
+			t.Errorf("orientation %d: expected the published image to display upright", orientation)
+		}
+	}
+}
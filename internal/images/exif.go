@@ -0,0 +1,331 @@
+package images
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata holds the EXIF, XMP and IPTC fields the site cares about.
+type Metadata struct {
+	CaptureTime time.Time
+	Exposure    Exposure
+	Location    *GeoPoint
+	Keywords    []string
+}
+
+// GeoPoint is a WGS84 position in decimal degrees.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Coarsen rounds the point to one decimal place (roughly 10km), enough to show
+// the area a photo was taken in without pinpointing it.
+func (p GeoPoint) Coarsen() GeoPoint {
+	return GeoPoint{
+		Latitude:  math.Round(p.Latitude*10) / 10,
+		Longitude: math.Round(p.Longitude*10) / 10,
+	}
+}
+
+// Exposure describes the camera settings a photo was taken with.
+type Exposure struct {
+	Make         string
+	Model        string
+	Lens         string
+	FNumber      float64
+	ExposureTime string
+	ISO          int
+	FocalLength  float64
+}
+
+// Details returns the exposure as short display strings, e.g. "f/2.8" or "ISO 100",
+// omitting anything the camera did not record.
+func (e Exposure) Details() []string {
+	var details []string
+	camera := e.Model
+	if e.Make != "" && !strings.HasPrefix(strings.ToLower(e.Model), strings.ToLower(e.Make)) {
+		camera = strings.TrimSpace(e.Make + " " + e.Model)
+	}
+	if camera != "" {
+		details = append(details, camera)
+	}
+	if e.Lens != "" {
+		details = append(details, e.Lens)
+	}
+	if e.FocalLength > 0 {
+		details = append(details, strconv.FormatFloat(e.FocalLength, 'f', -1, 64)+"mm")
+	}
+	if e.FNumber > 0 {
+		details = append(details, "f/"+strconv.FormatFloat(e.FNumber, 'f', -1, 64))
+	}
+	if e.ExposureTime != "" {
+		details = append(details, e.ExposureTime+"s")
+	}
+	if e.ISO > 0 {
+		details = append(details, "ISO "+strconv.Itoa(e.ISO))
+	}
+	return details
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExposureTime     = 0x829A
+	tagFNumber          = 0x829D
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920A
+	tagLensModel        = 0xA434
+)
+
+// Tags within the GPS IFD.
+const (
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+)
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// ReadMetadata extracts embedded metadata from a JPEG file. Files without
+// metadata (including PNGs) return an empty Metadata and no error.
+func ReadMetadata(path string) (Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer file.Close()
+
+	segments, err := ReadSegments(file)
+	if err != nil {
+		if errors.Is(err, ErrNotJPEG) {
+			return Metadata{}, nil
+		}
+		return Metadata{}, err
+	}
+
+	var meta Metadata
+	for _, segment := range segments {
+		switch {
+		case segment.IsExif():
+			parseExif(segment.Data[len(exifHeader):], &meta)
+		case segment.IsXMP():
+			meta.Keywords = append(meta.Keywords, parseXMPKeywords(segment.Data[len(xmpHeader):])...)
+		case segment.IsIPTC():
+			meta.Keywords = append(meta.Keywords, parseIPTCKeywords(segment.Data)...)
+		}
+	}
+	meta.Keywords = NormalizeKeywords(meta.Keywords)
+	return meta, nil
+}
+
+type tiffEntry struct {
+	tag      uint16
+	kind     uint16
+	count    uint32
+	valueRaw []byte
+}
+
+// byteOrder is implemented by binary.LittleEndian and binary.BigEndian.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type tiffReader struct {
+	data  []byte
+	order byteOrder
+}
+
+func newTIFFReader(data []byte) (*tiffReader, uint32, bool) {
+	if len(data) < 8 {
+		return nil, 0, false
+	}
+	var order byteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, false
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, 0, false
+	}
+	return &tiffReader{data: data, order: order}, order.Uint32(data[4:8]), true
+}
+
+var tiffTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+// readIFD returns the entries of the IFD at offset, keyed by tag.
+func (r *tiffReader) readIFD(offset uint32) map[uint16]tiffEntry {
+	entries := make(map[uint16]tiffEntry)
+	if int(offset)+2 > len(r.data) {
+		return entries
+	}
+	count := int(r.order.Uint16(r.data[offset:]))
+	for idx := 0; idx < count; idx++ {
+		start := int(offset) + 2 + idx*12
+		if start+12 > len(r.data) {
+			break
+		}
+		raw := r.data[start : start+12]
+		entry := tiffEntry{
+			tag:   r.order.Uint16(raw[0:2]),
+			kind:  r.order.Uint16(raw[2:4]),
+			count: r.order.Uint32(raw[4:8]),
+		}
+		size := uint64(tiffTypeSizes[entry.kind]) * uint64(entry.count)
+		if size <= 4 {
+			entry.valueRaw = raw[8 : 8+size]
+		} else {
+			valueOffset := uint64(r.order.Uint32(raw[8:12]))
+			if valueOffset+size > uint64(len(r.data)) {
+				continue
+			}
+			entry.valueRaw = r.data[valueOffset : valueOffset+size]
+		}
+		entries[entry.tag] = entry
+	}
+	return entries
+}
+
+func (r *tiffReader) uint32Value(entry tiffEntry) (uint32, bool) {
+	switch {
+	case entry.kind == 3 && len(entry.valueRaw) >= 2:
+		return uint32(r.order.Uint16(entry.valueRaw)), true
+	case entry.kind == 4 && len(entry.valueRaw) >= 4:
+		return r.order.Uint32(entry.valueRaw), true
+	}
+	return 0, false
+}
+
+// rationalValue returns the numerator and denominator of an unsigned RATIONAL entry.
+func (r *tiffReader) rationalValue(entry tiffEntry) (uint32, uint32, bool) {
+	if entry.kind != 5 || len(entry.valueRaw) < 8 {
+		return 0, 0, false
+	}
+	numerator, denominator := r.order.Uint32(entry.valueRaw[0:4]), r.order.Uint32(entry.valueRaw[4:8])
+	if denominator == 0 {
+		return 0, 0, false
+	}
+	return numerator, denominator, true
+}
+
+func (r *tiffReader) floatValue(entry tiffEntry) float64 {
+	numerator, denominator, ok := r.rationalValue(entry)
+	if !ok {
+		return 0
+	}
+	return math.Round(float64(numerator)/float64(denominator)*10) / 10
+}
+
+func (r *tiffReader) stringValue(entry tiffEntry) string {
+	if entry.kind != 2 {
+		return ""
+	}
+	return strings.TrimRight(string(entry.valueRaw), "\x00 ")
+}
+
+func parseExif(data []byte, meta *Metadata) {
+	reader, ifd0Offset, ok := newTIFFReader(data)
+	if !ok {
+		return
+	}
+
+	ifd0 := reader.readIFD(ifd0Offset)
+	if entry, ok := ifd0[tagDateTime]; ok {
+		meta.CaptureTime = parseExifTime(reader.stringValue(entry))
+	}
+	meta.Exposure.Make = reader.stringValue(ifd0[tagMake])
+	meta.Exposure.Model = reader.stringValue(ifd0[tagModel])
+
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		if exifOffset, ok := reader.uint32Value(entry); ok {
+			exifIFD := reader.readIFD(exifOffset)
+			if entry, ok := exifIFD[tagDateTimeOriginal]; ok {
+				if captured := parseExifTime(reader.stringValue(entry)); !captured.IsZero() {
+					meta.CaptureTime = captured
+				}
+			}
+			parseExposure(reader, exifIFD, &meta.Exposure)
+		}
+	}
+
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		if gpsOffset, ok := reader.uint32Value(entry); ok {
+			meta.Location = parseGPS(reader, reader.readIFD(gpsOffset))
+		}
+	}
+}
+
+func parseGPS(reader *tiffReader, gpsIFD map[uint16]tiffEntry) *GeoPoint {
+	latitude, latOK := reader.degreesValue(gpsIFD[tagGPSLatitude])
+	longitude, lonOK := reader.degreesValue(gpsIFD[tagGPSLongitude])
+	if !latOK || !lonOK {
+		return nil
+	}
+	if reader.stringValue(gpsIFD[tagGPSLatitudeRef]) == "S" {
+		latitude = -latitude
+	}
+	if reader.stringValue(gpsIFD[tagGPSLongitudeRef]) == "W" {
+		longitude = -longitude
+	}
+	return &GeoPoint{Latitude: latitude, Longitude: longitude}
+}
+
+// degreesValue converts a degrees/minutes/seconds triple of rationals to decimal degrees.
+func (r *tiffReader) degreesValue(entry tiffEntry) (float64, bool) {
+	if entry.kind != 5 || entry.count != 3 || len(entry.valueRaw) < 24 {
+		return 0, false
+	}
+	var degrees float64
+	for idx, scale := range []float64{1, 60, 3600} {
+		part := tiffEntry{kind: 5, count: 1, valueRaw: entry.valueRaw[idx*8 : idx*8+8]}
+		numerator, denominator, ok := r.rationalValue(part)
+		if !ok {
+			return 0, false
+		}
+		degrees += float64(numerator) / float64(denominator) / scale
+	}
+	return degrees, true
+}
+
+func parseExposure(reader *tiffReader, exifIFD map[uint16]tiffEntry, exposure *Exposure) {
+	exposure.Lens = reader.stringValue(exifIFD[tagLensModel])
+	exposure.FNumber = reader.floatValue(exifIFD[tagFNumber])
+	exposure.FocalLength = reader.floatValue(exifIFD[tagFocalLength])
+	if iso, ok := reader.uint32Value(exifIFD[tagISO]); ok {
+		exposure.ISO = int(iso)
+	}
+	if numerator, denominator, ok := reader.rationalValue(exifIFD[tagExposureTime]); ok && numerator > 0 {
+		if numerator < denominator {
+			exposure.ExposureTime = fmt.Sprintf("1/%d", int(math.Round(float64(denominator)/float64(numerator))))
+		} else {
+			exposure.ExposureTime = strconv.FormatFloat(float64(numerator)/float64(denominator), 'f', -1, 64)
+		}
+	}
+}
+
+func parseExifTime(value string) time.Time {
+	parsed, err := time.Parse(exifTimeLayout, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
@@ -0,0 +1,259 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exifWithCaptureTime builds a minimal EXIF block holding only DateTimeOriginal.
+func exifWithCaptureTime(captured time.Time) []byte {
+	return buildExif(nil, []testTIFFEntry{asciiEntry(tagDateTimeOriginal, captured.Format(exifTimeLayout))})
+}
+
+type testTIFFEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, value string) testTIFFEntry {
+	return testTIFFEntry{tag: tag, kind: 2, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+func shortEntry(tag uint16, value uint16) testTIFFEntry {
+	return testTIFFEntry{tag: tag, kind: 3, count: 1, value: binary.LittleEndian.AppendUint16(nil, value)}
+}
+
+func rationalEntry(tag uint16, numerator, denominator uint32) testTIFFEntry {
+	value := binary.LittleEndian.AppendUint32(nil, numerator)
+	return testTIFFEntry{tag: tag, kind: 5, count: 1, value: binary.LittleEndian.AppendUint32(value, denominator)}
+}
+
+// buildExif lays out a little-endian EXIF block with an IFD0 and, when exifIFD
+// is non-empty, an Exif sub-IFD linked from IFD0.
+func buildExif(ifd0 []testTIFFEntry, exifIFD []testTIFFEntry) []byte {
+	return buildExifWithGPS(ifd0, exifIFD, nil)
+}
+
+// buildExifWithGPS is buildExif with an optional GPS sub-IFD linked from IFD0.
+func buildExifWithGPS(ifd0, exifIFD, gpsIFD []testTIFFEntry) []byte {
+	le := binary.LittleEndian
+	ifdSize := func(entries []testTIFFEntry) uint32 { return uint32(2 + 12*len(entries) + 4) }
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, testTIFFEntry{tag: tagExifIFD, kind: 4, count: 1})
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, testTIFFEntry{tag: tagGPSIFD, kind: 4, count: 1})
+	}
+
+	ifd0Offset := uint32(8)
+	exifOffset := ifd0Offset + ifdSize(ifd0)
+	gpsOffset := exifOffset
+	if len(exifIFD) > 0 {
+		gpsOffset += ifdSize(exifIFD)
+	}
+	dataOffset := gpsOffset
+	if len(gpsIFD) > 0 {
+		dataOffset += ifdSize(gpsIFD)
+	}
+
+	var data bytes.Buffer
+	writeIFD := func(out *bytes.Buffer, entries []testTIFFEntry) {
+		binary.Write(out, le, uint16(len(entries)))
+		for _, entry := range entries {
+			binary.Write(out, le, []uint16{entry.tag, entry.kind})
+			binary.Write(out, le, entry.count)
+			value := entry.value
+			switch entry.tag {
+			case tagExifIFD:
+				value = le.AppendUint32(nil, exifOffset)
+			case tagGPSIFD:
+				value = le.AppendUint32(nil, gpsOffset)
+			}
+			if len(value) <= 4 {
+				padded := make([]byte, 4)
+				copy(padded, value)
+				out.Write(padded)
+				continue
+			}
+			binary.Write(out, le, dataOffset+uint32(data.Len()))
+			data.Write(value)
+		}
+		binary.Write(out, le, uint32(0))
+	}
+
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, le, uint16(42))
+	binary.Write(&tiff, le, ifd0Offset)
+	writeIFD(&tiff, ifd0)
+	if len(exifIFD) > 0 {
+		writeIFD(&tiff, exifIFD)
+	}
+	if len(gpsIFD) > 0 {
+		writeIFD(&tiff, gpsIFD)
+	}
+	tiff.Write(data.Bytes())
+
+	return append(append([]byte{}, exifHeader...), tiff.Bytes()...)
+}
+
+// degreesEntry encodes whole degrees, minutes and seconds as three rationals.
+func degreesEntry(tag uint16, degrees, minutes, seconds uint32) testTIFFEntry {
+	var value []byte
+	for _, part := range []uint32{degrees, minutes, seconds} {
+		value = binary.LittleEndian.AppendUint32(value, part)
+		value = binary.LittleEndian.AppendUint32(value, 1)
+	}
+	return testTIFFEntry{tag: tag, kind: 5, count: 3, value: value}
+}
+
+func writeJPEGWithSegments(t *testing.T, path string, segments []Segment) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := InsertSegments(&out, []byte{0xFF, 0xD8, 0xFF, 0xD9}, segments); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadMetadata_ReadsDateTimeOriginal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dated.jpg")
+	captured := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	writeJPEGWithSegments(t, path, []Segment{{Marker: 0xE1, Data: exifWithCaptureTime(captured)}})
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if !meta.CaptureTime.Equal(captured) {
+		t.Errorf("expected capture time %v, got %v", captured, meta.CaptureTime)
+	}
+}
+
+func TestReadMetadata_NoExifReturnsZeroValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.png")
+	createDummyImage(t, path, 10, 10)
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("expected no error for PNG, got %v", err)
+	}
+	if !meta.CaptureTime.IsZero() {
+		t.Errorf("expected zero capture time, got %v", meta.CaptureTime)
+	}
+}
+
+func TestInsertSegments_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segments.jpg")
+	segment := Segment{Marker: 0xE1, Data: exifWithCaptureTime(time.Now())}
+	writeJPEGWithSegments(t, path, []Segment{segment})
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	segments, err := ReadSegments(file)
+	if err != nil {
+		t.Fatalf("ReadSegments failed: %v", err)
+	}
+	if len(segments) != 1 || !segments[0].IsExif() || !bytes.Equal(segments[0].Data, segment.Data) {
+		t.Errorf("expected the inserted EXIF segment back, got %+v", segments)
+	}
+}
+
+func TestReadMetadata_ReadsExposure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exposure.jpg")
+	exif := buildExif(
+		[]testTIFFEntry{asciiEntry(tagMake, "SONY"), asciiEntry(tagModel, "ILCE-7M3")},
+		[]testTIFFEntry{
+			rationalEntry(tagExposureTime, 10, 2500),
+			rationalEntry(tagFNumber, 28, 10),
+			shortEntry(tagISO, 400),
+			rationalEntry(tagFocalLength, 70, 1),
+			asciiEntry(tagLensModel, "FE 24-70mm F2.8 GM"),
+		},
+	)
+	writeJPEGWithSegments(t, path, []Segment{{Marker: 0xE1, Data: exif}})
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+
+	expected := Exposure{
+		Make:         "SONY",
+		Model:        "ILCE-7M3",
+		Lens:         "FE 24-70mm F2.8 GM",
+		FNumber:      2.8,
+		ExposureTime: "1/250",
+		ISO:          400,
+		FocalLength:  70,
+	}
+	if meta.Exposure != expected {
+		t.Errorf("expected exposure %+v, got %+v", expected, meta.Exposure)
+	}
+
+	details := strings.Join(meta.Exposure.Details(), " | ")
+	if details != "SONY ILCE-7M3 | FE 24-70mm F2.8 GM | 70mm | f/2.8 | 1/250s | ISO 400" {
+		t.Errorf("unexpected exposure details: %s", details)
+	}
+}
+
+func TestExposure_DetailsSkipsRepeatedMakeAndMissingFields(t *testing.T) {
+	details := Exposure{Make: "Canon", Model: "Canon EOS R5", ISO: 100}.Details()
+	if strings.Join(details, " | ") != "Canon EOS R5 | ISO 100" {
+		t.Errorf("unexpected exposure details: %v", details)
+	}
+}
+
+func TestReadMetadata_ReadsGPSLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geotagged.jpg")
+	exif := buildExifWithGPS(nil, nil, []testTIFFEntry{
+		asciiEntry(tagGPSLatitudeRef, "N"),
+		degreesEntry(tagGPSLatitude, 61, 13, 12),
+		asciiEntry(tagGPSLongitudeRef, "W"),
+		degreesEntry(tagGPSLongitude, 149, 53, 24),
+	})
+	writeJPEGWithSegments(t, path, []Segment{{Marker: 0xE1, Data: exif}})
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if meta.Location == nil {
+		t.Fatal("expected a location")
+	}
+	if math.Abs(meta.Location.Latitude-61.22) > 1e-9 || math.Abs(meta.Location.Longitude+149.89) > 1e-9 {
+		t.Errorf("expected 61.22,-149.89, got %v,%v", meta.Location.Latitude, meta.Location.Longitude)
+	}
+
+	coarse := meta.Location.Coarsen()
+	if coarse.Latitude != 61.2 || coarse.Longitude != -149.9 {
+		t.Errorf("expected coarse 61.2,-149.9, got %v,%v", coarse.Latitude, coarse.Longitude)
+	}
+}
+
+func TestReadMetadata_NoGPSLeavesLocationNil(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dated.jpg")
+	writeJPEGWithSegments(t, path, []Segment{{Marker: 0xE1, Data: exifWithCaptureTime(time.Now())}})
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if meta.Location != nil {
+		t.Errorf("expected no location, got %v", *meta.Location)
+	}
+}
@@ -0,0 +1,72 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// fingerprintBytes is how much of a file's SHA-256 its fingerprint carries.
+const fingerprintBytes = 8
+
+// fingerprint is a file's fingerprint as of its size and modification time.
+type fingerprint struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+var (
+	fingerprintsMu sync.Mutex
+	fingerprints   = map[string]fingerprint{}
+)
+
+// FingerprintData returns the hex hash identifying data, e.g. "1f2e3d4c5b6a7988".
+// It serves as images' ETags and as the "?v=" that fingerprints their URLs.
+func FingerprintData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:fingerprintBytes])
+}
+
+// Fingerprint returns the FingerprintData of the file at path.
+func Fingerprint(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return FingerprintFile(file)
+}
+
+// FingerprintFile returns the FingerprintData of file and leaves it positioned
+// at its start. Fingerprints are kept while a file's size and modification
+// time stay the same, so unchanged files are only read once.
+func FingerprintFile(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	fingerprintsMu.Lock()
+	known, ok := fingerprints[file.Name()]
+	fingerprintsMu.Unlock()
+	if ok && known.size == info.Size() && known.modTime.Equal(info.ModTime()) {
+		return known.hash, nil
+	}
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(digest.Sum(nil)[:fingerprintBytes])
+
+	fingerprintsMu.Lock()
+	fingerprints[file.Name()] = fingerprint{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	fingerprintsMu.Unlock()
+	return hash, nil
+}
@@ -0,0 +1,35 @@
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "DSC01_w600.jpg")
+	if err := os.WriteFile(path, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := Fingerprint(path)
+	if err != nil || first != FingerprintData([]byte("first")) || len(first) != 2*fingerprintBytes {
+		t.Fatalf("expected the content's hash, got %q, %v", first, err)
+	}
+
+	// A re-published file has a new modification time as well as new content.
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if second, err := Fingerprint(path); err != nil || second == first || second != FingerprintData([]byte("other")) {
+		t.Errorf("expected a changed file to be hashed again, got %q, %v", second, err)
+	}
+
+	if _, err := Fingerprint(filepath.Join(t.TempDir(), "missing.jpg")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,128 @@
+package images
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// FocalPoint is the point of interest of an image, as fractions of its width
+// and height measured from the top-left corner.
+type FocalPoint struct {
+	X, Y float64
+}
+
+// CenterFocus is the focal point of images with no detail to go by.
+var CenterFocus = FocalPoint{X: 0.5, Y: 0.5}
+
+// Valid reports whether the point lies within the image.
+func (f FocalPoint) Valid() bool {
+	return f.X >= 0 && f.X <= 1 && f.Y >= 0 && f.Y <= 1
+}
+
+// focusSampleSize is the longest side, in pixels, of the copy EntropyFocus scans.
+const focusSampleSize = 96
+
+// focusCellSize is the side, in sample pixels, of the cells entropy is measured over.
+const focusCellSize = 8
+
+// EntropyFocus estimates where the subject of an image is. Busy, detailed areas
+// score higher than sky, water or blurred backgrounds, so the result is the
+// centre of the image's cells weighted by the square of their grey-level entropy.
+func EntropyFocus(img image.Image) FocalPoint {
+	sample := imaging.Grayscale(imaging.Fit(img, focusSampleSize, focusSampleSize, imaging.Box))
+	bounds := sample.Bounds()
+
+	var sumX, sumY, total float64
+	for top := bounds.Min.Y; top < bounds.Max.Y; top += focusCellSize {
+		for left := bounds.Min.X; left < bounds.Max.X; left += focusCellSize {
+			cell := image.Rect(left, top, left+focusCellSize, top+focusCellSize).Intersect(bounds)
+			weight := cellEntropy(sample, cell)
+			weight *= weight
+			sumX += weight * float64(cell.Min.X+cell.Max.X) / 2
+			sumY += weight * float64(cell.Min.Y+cell.Max.Y) / 2
+			total += weight
+		}
+	}
+	if total == 0 {
+		return CenterFocus
+	}
+	return FocalPoint{
+		X: (sumX/total - float64(bounds.Min.X)) / float64(bounds.Dx()),
+		Y: (sumY/total - float64(bounds.Min.Y)) / float64(bounds.Dy()),
+	}
+}
+
+// cellEntropy is the Shannon entropy, in bits, of the grey levels in a cell of
+// a greyscale image, bucketed into 16 levels.
+func cellEntropy(img *image.NRGBA, cell image.Rectangle) float64 {
+	var histogram [16]int
+	for y := cell.Min.Y; y < cell.Max.Y; y++ {
+		for x := cell.Min.X; x < cell.Max.X; x++ {
+			histogram[img.Pix[img.PixOffset(x, y)]>>4]++
+		}
+	}
+
+	count := float64(cell.Dx() * cell.Dy())
+	entropy := 0.0
+	for _, n := range histogram {
+		if n > 0 {
+			p := float64(n) / count
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// AspectRatio is a width to height ratio such as 3:2.
+type AspectRatio struct {
+	Width, Height int
+}
+
+// ParseAspectRatio parses a ratio written as "width:height", e.g. "3:2".
+func ParseAspectRatio(s string) (AspectRatio, error) {
+	width, height, ok := strings.Cut(s, ":")
+	if !ok {
+		return AspectRatio{}, fmt.Errorf("aspect ratio %q is not width:height", s)
+	}
+	w, err := strconv.Atoi(width)
+	if err != nil || w <= 0 {
+		return AspectRatio{}, fmt.Errorf("aspect ratio %q has an invalid width", s)
+	}
+	h, err := strconv.Atoi(height)
+	if err != nil || h <= 0 {
+		return AspectRatio{}, fmt.Errorf("aspect ratio %q has an invalid height", s)
+	}
+	return AspectRatio{Width: w, Height: h}, nil
+}
+
+func (a AspectRatio) String() string {
+	return fmt.Sprintf("%d:%d", a.Width, a.Height)
+}
+
+// CropToAspect crops img to the aspect ratio, keeping as much of it as fits and
+// centring the crop on focus as far as the edges allow.
+func CropToAspect(img image.Image, aspect AspectRatio, focus FocalPoint) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*aspect.Height > height*aspect.Width {
+		width = height * aspect.Width / aspect.Height
+	} else {
+		height = width * aspect.Height / aspect.Width
+	}
+
+	left := cropStart(bounds.Min.X, bounds.Dx(), width, focus.X)
+	top := cropStart(bounds.Min.Y, bounds.Dy(), height, focus.Y)
+	return imaging.Crop(img, image.Rect(left, top, left+width, top+height))
+}
+
+// cropStart positions a span of length size within one of length full, starting
+// at origin, so that it is centred on the fraction focus without leaving the image.
+func cropStart(origin, full, size int, focus float64) int {
+	start := int(math.Round(focus*float64(full))) - size/2
+	return origin + max(0, min(start, full-size))
+}
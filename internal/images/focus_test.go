@@ -0,0 +1,69 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// texturedImage is a flat grey image with a noisy patch covering rect.
+func texturedImage(w, h int, rect image.Rectangle) *image.NRGBA {
+	img := solidImage(w, h, color.NRGBA{128, 128, 128, 255})
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			v := uint8((x*37 + y*91 + x*y*13) % 256)
+			img.Set(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestEntropyFocus(t *testing.T) {
+	focus := EntropyFocus(texturedImage(1200, 800, image.Rect(900, 100, 1100, 300)))
+	if math.Abs(focus.X-0.83) > 0.05 || math.Abs(focus.Y-0.25) > 0.05 {
+		t.Errorf("expected focus on the detailed patch near (0.83, 0.25); got %+v", focus)
+	}
+
+	if focus := EntropyFocus(solidImage(300, 200, color.White)); focus != CenterFocus {
+		t.Errorf("expected a flat image to be centred; got %+v", focus)
+	}
+}
+
+func TestCropToAspect(t *testing.T) {
+	src := solidImage(1200, 1200, color.White)
+	src.Set(1199, 1199, color.Black)
+
+	tests := []struct {
+		aspect AspectRatio
+		focus  FocalPoint
+		want   image.Rectangle
+	}{
+		{AspectRatio{3, 2}, CenterFocus, image.Rect(0, 300, 1200, 1100)},
+		{AspectRatio{3, 2}, FocalPoint{0.5, 1}, image.Rect(0, 400, 1200, 1200)},
+		{AspectRatio{1, 2}, FocalPoint{0, 0.5}, image.Rect(0, 0, 600, 1200)},
+	}
+	for _, tt := range tests {
+		cropped := CropToAspect(src, tt.aspect, tt.focus)
+		if cropped.Bounds().Size() != tt.want.Size() {
+			t.Errorf("%v at %+v: expected %v; got %v", tt.aspect, tt.focus, tt.want.Size(), cropped.Bounds().Size())
+			continue
+		}
+		// The bottom-right pixel marks whether the crop reached that corner.
+		corner := cropped.At(cropped.Bounds().Max.X-1, cropped.Bounds().Max.Y-1) == color.NRGBA{0, 0, 0, 255}
+		if corner != (tt.want.Max == image.Pt(1200, 1200)) {
+			t.Errorf("%v at %+v: expected the crop %v", tt.aspect, tt.focus, tt.want)
+		}
+	}
+}
+
+func TestParseAspectRatio(t *testing.T) {
+	if aspect, err := ParseAspectRatio("3:2"); err != nil || aspect != (AspectRatio{3, 2}) || aspect.String() != "3:2" {
+		t.Errorf("expected 3:2; got %v, %v", aspect, err)
+	}
+	for _, invalid := range []string{"", "3", "3:0", "-1:2", "a:b"} {
+		if _, err := ParseAspectRatio(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
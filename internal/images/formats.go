@@ -0,0 +1,122 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Format is a modern image format that variants are also published in, for
+// browsers that accept it. Encoding needs the format's command-line encoder.
+type Format struct {
+	Name     string // also the file extension, e.g. "webp"
+	MIMEType string
+	encoder  string
+	args     func(src, dst string) []string
+	// installed caches the PATH lookup of encoder.
+	installed func() bool
+}
+
+var (
+	AVIF = Format{
+		Name:      "avif",
+		MIMEType:  "image/avif",
+		encoder:   "avifenc",
+		installed: lookPathOnce("avifenc"),
+		args: func(src, dst string) []string {
+			return []string{"--ignore-exif", "--ignore-xmp", "-q", "60", src, dst}
+		},
+	}
+	WebP = Format{
+		Name:      "webp",
+		MIMEType:  "image/webp",
+		encoder:   "cwebp",
+		installed: lookPathOnce("cwebp"),
+		// Keep the ICC profiles of variants that could not be converted to sRGB.
+		args: func(src, dst string) []string {
+			return []string{"-quiet", "-q", "80", "-metadata", "icc", src, "-o", dst}
+		},
+	}
+)
+
+// ModernFormats lists the formats variants are published in, most preferred first.
+var ModernFormats = []Format{AVIF, WebP}
+
+// Available reports whether the format's encoder is installed.
+func (f Format) Available() bool {
+	return f.installed()
+}
+
+func lookPathOnce(command string) func() bool {
+	return sync.OnceValue(func() bool {
+		_, err := exec.LookPath(command)
+		return err == nil
+	})
+}
+
+// Sibling returns the path of the variant at path in this format, e.g.
+// "DSC01_w600.webp" for "DSC01_w600.jpg".
+func (f Format) Sibling(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + f.Name
+}
+
+// EncodeSibling writes the image at path in this format next to it, unless an
+// up-to-date copy is there, and returns the copy's path.
+func (f Format) EncodeSibling(path string) (string, error) {
+	dst := f.Sibling(path)
+	srcInfo, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if dstInfo, err := os.Stat(dst); err == nil && dstInfo.ModTime().After(srcInfo.ModTime()) {
+		return dst, nil
+	}
+
+	// Encode to a temporary file so that readers never see a partial image.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*."+f.Name)
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if output, err := exec.Command(f.encoder, f.args(path, tmp.Name())...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", f.encoder, err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// NegotiateFormat picks the first of candidates that the Accept header lists
+// explicitly. Wildcards are ignored: browsers that send only "image/*" do not
+// all decode the newer formats.
+func NegotiateFormat(accept string, candidates []Format) (Format, bool) {
+	accepted := map[string]bool{}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			accepted[strings.ToLower(strings.TrimSpace(mediaType))] = true
+		}
+	}
+
+	for _, format := range candidates {
+		if accepted[format.MIMEType] {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
@@ -0,0 +1,55 @@
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept     string
+		candidates []Format
+		want       string
+	}{
+		{"image/avif,image/webp,image/apng,image/*,*/*;q=0.8", ModernFormats, "avif"},
+		{"image/avif,image/webp,*/*", []Format{WebP}, "webp"},
+		{"image/webp,*/*", ModernFormats, "webp"},
+		{"image/avif;q=0, image/webp", ModernFormats, "webp"},
+		{"IMAGE/WEBP", ModernFormats, "webp"},
+		{"image/*,*/*;q=0.8", ModernFormats, ""},
+		{"", ModernFormats, ""},
+		{"image/avif,image/webp", nil, ""},
+	}
+	for _, tt := range tests {
+		format, ok := NegotiateFormat(tt.accept, tt.candidates)
+		if format.Name != tt.want || ok != (tt.want != "") {
+			t.Errorf("%q: expected %q; got %q, %v", tt.accept, tt.want, format.Name, ok)
+		}
+	}
+}
+
+func TestFormat_Sibling(t *testing.T) {
+	if sibling := WebP.Sibling(filepath.Join("Alaska", "denali_w600.jpg")); sibling != filepath.Join("Alaska", "denali_w600.webp") {
+		t.Errorf("unexpected sibling %s", sibling)
+	}
+}
+
+func TestFormat_EncodeSibling(t *testing.T) {
+	for _, format := range ModernFormats {
+		if !format.Available() {
+			t.Logf("%s encoder not installed; skipping", format.Name)
+			continue
+		}
+		path := filepath.Join(t.TempDir(), "photo_w600.png")
+		createDummyImage(t, path, 64, 48)
+
+		encoded, err := format.EncodeSibling(path)
+		if err != nil {
+			t.Fatalf("%s: EncodeSibling failed: %v", format.Name, err)
+		}
+		if info, err := os.Stat(encoded); err != nil || info.Size() == 0 || encoded != format.Sibling(path) {
+			t.Errorf("%s: expected an encoded sibling at %s; got %s", format.Name, format.Sibling(path), encoded)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// iccHeader starts each APP2 segment holding a chunk of an ICC profile. A
+// one-based chunk number and the chunk count follow it.
+var iccHeader = []byte("ICC_PROFILE\x00")
+
+// iccChunkSize is the most profile data one APP2 segment holds.
+const iccChunkSize = 0xFFFF - 2 - 14
+
+// IsICC reports whether the segment is an APP2 chunk of an ICC profile.
+func (s Segment) IsICC() bool {
+	return s.Marker == 0xE2 && bytes.HasPrefix(s.Data, iccHeader) && len(s.Data) >= len(iccHeader)+2
+}
+
+// colorProfile is an embedded ICC profile. Matrix/TRC RGB profiles, which is
+// what Adobe RGB, Display P3 and ProPhoto exports carry, can be converted to
+// sRGB; others can only be embedded again.
+type colorProfile struct {
+	data []byte
+	// toXYZ maps linear RGB to D50 XYZ, by column; nil when not convertible.
+	toXYZ  *[3][3]float64
+	curves [3]toneCurve
+}
+
+// readColorProfile returns the ICC profile embedded in the JPEG at path, or
+// nil when it has none.
+func readColorProfile(path string) *colorProfile {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	segments, err := ReadSegments(file)
+	if err != nil {
+		return nil
+	}
+	data := joinICCChunks(segments)
+	if data == nil {
+		return nil
+	}
+	return parseColorProfile(data)
+}
+
+// joinICCChunks reassembles the profile split across segments in chunk order.
+func joinICCChunks(segments []Segment) []byte {
+	var chunks []Segment
+	for _, segment := range segments {
+		if segment.IsICC() {
+			chunks = append(chunks, segment)
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	sort.SliceStable(chunks, func(idx, jdx int) bool {
+		return chunks[idx].Data[len(iccHeader)] < chunks[jdx].Data[len(iccHeader)]
+	})
+	var data []byte
+	for _, chunk := range chunks {
+		data = append(data, chunk.Data[len(iccHeader)+2:]...)
+	}
+	return data
+}
+
+// parseColorProfile reads the colour space of an ICC profile.
+func parseColorProfile(data []byte) *colorProfile {
+	profile := &colorProfile{data: data}
+	if len(data) < 132 || string(data[16:20]) != "RGB " || string(data[20:24]) != "XYZ " {
+		return profile
+	}
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	for idx := 0; idx < count; idx++ {
+		start := 132 + idx*12
+		if start+12 > len(data) {
+			break
+		}
+		offset := uint64(binary.BigEndian.Uint32(data[start+4:]))
+		size := uint64(binary.BigEndian.Uint32(data[start+8:]))
+		if offset+size > uint64(len(data)) {
+			continue
+		}
+		tags[string(data[start:start+4])] = data[offset : offset+size]
+	}
+
+	var toXYZ [3][3]float64
+	for channel, name := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz, ok := parseXYZ(tags[name])
+		if !ok {
+			return profile
+		}
+		toXYZ[channel] = xyz
+	}
+	for channel, name := range []string{"rTRC", "gTRC", "bTRC"} {
+		curve, ok := parseToneCurve(tags[name])
+		if !ok {
+			return profile
+		}
+		profile.curves[channel] = curve
+	}
+	profile.toXYZ = &toXYZ
+	return profile
+}
+
+func s15Fixed16(raw []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(raw))) / 65536
+}
+
+// parseXYZ reads an XYZType tag.
+func parseXYZ(tag []byte) ([3]float64, bool) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, false
+	}
+	return [3]float64{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, true
+}
+
+// toneCurve maps an encoded channel value in [0, 1] to linear light.
+type toneCurve func(float64) float64
+
+// parseToneCurve reads a curveType or parametricCurveType tag.
+func parseToneCurve(tag []byte) (toneCurve, bool) {
+	if len(tag) < 12 {
+		return nil, false
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		count := int(binary.BigEndian.Uint32(tag[8:12]))
+		if len(tag) < 12+2*count {
+			return nil, false
+		}
+		switch count {
+		case 0:
+			return func(v float64) float64 { return v }, true
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, true
+		}
+		table := make([]float64, count)
+		for idx := range table {
+			table[idx] = float64(binary.BigEndian.Uint16(tag[12+2*idx:])) / 65535
+		}
+		return func(v float64) float64 {
+			pos := v * float64(count-1)
+			idx := int(pos)
+			if idx >= count-1 {
+				return table[count-1]
+			}
+			return table[idx] + (table[idx+1]-table[idx])*(pos-float64(idx))
+		}, true
+	case "para":
+		// The parameters are g, a, b, c, d, e, f, as many as the function uses.
+		paramCounts := []int{1, 3, 4, 5, 7}
+		function := int(binary.BigEndian.Uint16(tag[8:10]))
+		if function >= len(paramCounts) || len(tag) < 12+4*paramCounts[function] {
+			return nil, false
+		}
+		var p [7]float64
+		for idx := 0; idx < paramCounts[function]; idx++ {
+			p[idx] = s15Fixed16(tag[12+4*idx:])
+		}
+		g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		switch function {
+		case 0:
+			return func(v float64) float64 { return math.Pow(v, g) }, true
+		case 1:
+			return func(v float64) float64 {
+				if a != 0 && v >= -b/a {
+					return math.Pow(a*v+b, g)
+				}
+				return 0
+			}, true
+		case 2:
+			return func(v float64) float64 {
+				if a != 0 && v >= -b/a {
+					return math.Pow(a*v+b, g) + c
+				}
+				return c
+			}, true
+		case 3:
+			return func(v float64) float64 {
+				if v >= d {
+					return math.Pow(a*v+b, g)
+				}
+				return c * v
+			}, true
+		case 4:
+			return func(v float64) float64 {
+				if v >= d {
+					return math.Pow(a*v+b, g) + e
+				}
+				return c*v + f
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// srgbToXYZ is sRGB's linear RGB to D50 XYZ matrix, by column, as sRGB ICC
+// profiles give it.
+var srgbToXYZ = [3][3]float64{
+	{0.4360747, 0.2225045, 0.0139322},
+	{0.3850649, 0.7168786, 0.0971045},
+	{0.1430804, 0.0606169, 0.7141733},
+}
+
+// srgbFromXYZ inverts srgbToXYZ, giving D50 XYZ to linear sRGB by row.
+var srgbFromXYZ = sync.OnceValue(func() [3][3]float64 {
+	var a [3][3]float64
+	for row := range 3 {
+		for col := range 3 {
+			a[row][col] = srgbToXYZ[col][row]
+		}
+	}
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+	return [3][3]float64{
+		{(a[1][1]*a[2][2] - a[1][2]*a[2][1]) / det, (a[0][2]*a[2][1] - a[0][1]*a[2][2]) / det, (a[0][1]*a[1][2] - a[0][2]*a[1][1]) / det},
+		{(a[1][2]*a[2][0] - a[1][0]*a[2][2]) / det, (a[0][0]*a[2][2] - a[0][2]*a[2][0]) / det, (a[0][2]*a[1][0] - a[0][0]*a[1][2]) / det},
+		{(a[1][0]*a[2][1] - a[1][1]*a[2][0]) / det, (a[0][1]*a[2][0] - a[0][0]*a[2][1]) / det, (a[0][0]*a[1][1] - a[0][1]*a[1][0]) / det},
+	}
+})
+
+// srgbEncode is the sRGB transfer function, from linear light to [0, 1].
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func srgbDecode(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// srgbEncodeTable encodes linear light in 1/65535 steps to 8 bits, fine
+// enough that shadows keep every level.
+var srgbEncodeTable = sync.OnceValue(func() []uint8 {
+	table := make([]uint8, 65536)
+	for idx := range table {
+		table[idx] = uint8(math.Round(srgbEncode(float64(idx)/65535) * 255))
+	}
+	return table
+})
+
+// convertible reports whether pixels can be converted from the profile to sRGB.
+func (p *colorProfile) convertible() bool {
+	return p != nil && p.toXYZ != nil
+}
+
+// isSRGB reports whether the profile describes sRGB, so there is nothing to convert.
+func (p *colorProfile) isSRGB() bool {
+	if !p.convertible() {
+		return false
+	}
+	for channel := range 3 {
+		for component := range 3 {
+			if math.Abs(p.toXYZ[channel][component]-srgbToXYZ[channel][component]) > 0.002 {
+				return false
+			}
+		}
+		for _, v := range []float64{0.02, 0.2, 0.5, 0.8} {
+			if math.Abs(p.curves[channel](v)-srgbDecode(v)) > 0.002 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toSRGB returns img with its colours converted from the profile to sRGB.
+// Colours outside sRGB are clipped.
+func (p *colorProfile) toSRGB(img image.Image) *image.NRGBA {
+	var linear [3][256]float64
+	for channel := range 3 {
+		for v := range 256 {
+			linear[channel][v] = p.curves[channel](float64(v) / 255)
+		}
+	}
+	// Combine the profile's matrix with sRGB's inverse: RGB to sRGB, by row.
+	from := srgbFromXYZ()
+	var m [3][3]float64
+	for row := range 3 {
+		for col := range 3 {
+			for k := range 3 {
+				m[row][col] += from[row][k] * p.toXYZ[col][k]
+			}
+		}
+	}
+	encode := srgbEncodeTable()
+
+	dst := imaging.Clone(img)
+	for offset := 0; offset+3 < len(dst.Pix); offset += 4 {
+		px := dst.Pix[offset : offset+3 : offset+3]
+		r, g, b := linear[0][px[0]], linear[1][px[1]], linear[2][px[2]]
+		for row := range 3 {
+			v := m[row][0]*r + m[row][1]*g + m[row][2]*b
+			px[row] = encode[int(math.Round(math.Min(math.Max(v, 0), 1)*65535))]
+		}
+	}
+	return dst
+}
+
+// segments splits the profile into APP2 segments for embedding in a JPEG.
+func (p *colorProfile) segments() []Segment {
+	count := (len(p.data) + iccChunkSize - 1) / iccChunkSize
+	if count > 255 {
+		return nil
+	}
+	segments := make([]Segment, 0, count)
+	for idx := range count {
+		chunk := p.data[idx*iccChunkSize : min((idx+1)*iccChunkSize, len(p.data))]
+		data := append(append([]byte{}, iccHeader...), byte(idx+1), byte(count))
+		segments = append(segments, Segment{Marker: 0xE2, Data: append(data, chunk...)})
+	}
+	return segments
+}
+
+// ProfileSegments returns the ICC profile to embed in images made from the
+// JPEG at path. Open converts matrix-based profiles such as Adobe RGB to sRGB,
+// the web's default, so only profiles it cannot convert are returned.
+func ProfileSegments(path string) []Segment {
+	profile := readColorProfile(path)
+	if profile == nil || profile.convertible() {
+		return nil
+	}
+	return profile.segments()
+}
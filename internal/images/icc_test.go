@@ -0,0 +1,159 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// adobeRGBColumns are Adobe RGB (1998)'s primaries in D50 XYZ, as its ICC
+// profile gives them.
+var adobeRGBColumns = [3][3]float64{
+	{0.6097412, 0.3111115, 0.0194702},
+	{0.2052765, 0.6256714, 0.0608673},
+	{0.1491852, 0.0632172, 0.7445679},
+}
+
+// iccProfile lays out an RGB display profile holding the given tags.
+func iccProfile(tags map[string][]byte, names []string) []byte {
+	offset := 132 + 12*len(names)
+	var table, data bytes.Buffer
+	for _, name := range names {
+		table.WriteString(name)
+		binary.Write(&table, binary.BigEndian, []uint32{uint32(offset + data.Len()), uint32(len(tags[name]))})
+		data.Write(tags[name])
+	}
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header, uint32(offset+data.Len()))
+	copy(header[12:], "mntrRGB XYZ ")
+	copy(header[36:], "acsp")
+	profile := append(header, binary.BigEndian.AppendUint32(nil, uint32(len(names)))...)
+	profile = append(profile, table.Bytes()...)
+	return append(profile, data.Bytes()...)
+}
+
+// matrixProfile builds a matrix/TRC profile with a single gamma for every channel.
+func matrixProfile(columns [3][3]float64, gamma float64) []byte {
+	tags := make(map[string][]byte)
+	for channel, name := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		tag := append([]byte("XYZ "), 0, 0, 0, 0)
+		for _, v := range columns[channel] {
+			tag = binary.BigEndian.AppendUint32(tag, uint32(int32(v*65536)))
+		}
+		tags[name] = tag
+	}
+	curve := append([]byte("curv"), 0, 0, 0, 0, 0, 0, 0, 1)
+	curve = binary.BigEndian.AppendUint16(curve, uint16(gamma*256))
+	for _, name := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags[name] = append(curve, 0, 0)
+	}
+	return iccProfile(tags, []string{"rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"})
+}
+
+// writeProfiledJPEG writes a patch of one colour tagged with profile, if any.
+func writeProfiledJPEG(t *testing.T, path string, patch color.NRGBA, profile []byte) {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, imaging.New(32, 32, patch), &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	var segments []Segment
+	if profile != nil {
+		segments = (&colorProfile{data: profile}).segments()
+	}
+	var out bytes.Buffer
+	if err := InsertSegments(&out, encoded.Bytes(), segments); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertPatch checks the colour at the centre of img within JPEG's error.
+func assertPatch(t *testing.T, label string, img image.Image, want color.NRGBA) {
+	t.Helper()
+	bounds := img.Bounds()
+	got := color.NRGBAModel.Convert(img.At(bounds.Dx()/2, bounds.Dy()/2)).(color.NRGBA)
+	near := func(a, b uint8) bool { return int(a)-int(b) <= 3 && int(b)-int(a) <= 3 }
+	if !near(got.R, want.R) || !near(got.G, want.G) || !near(got.B, want.B) {
+		t.Errorf("%s: expected about %v, got %v", label, want, got)
+	}
+}
+
+func TestOpen_ConvertsAdobeRGBToSRGB(t *testing.T) {
+	dir := t.TempDir()
+	patch := color.NRGBA{200, 120, 60, 255}
+	adobe := filepath.Join(dir, "adobe.jpg")
+	writeProfiledJPEG(t, adobe, patch, matrixProfile(adobeRGBColumns, 563.0/256))
+	untagged := filepath.Join(dir, "untagged.jpg")
+	writeProfiledJPEG(t, untagged, patch, nil)
+	srgb := filepath.Join(dir, "srgb.jpg")
+	writeProfiledJPEG(t, srgb, patch, matrixProfile(srgbToXYZ, 2.2))
+
+	img, err := Open(adobe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adobe RGB (200, 120, 60) is sRGB (224, 121, 53): redder than the same
+	// numbers read as sRGB.
+	assertPatch(t, "adobe rgb", img, color.NRGBA{224, 121, 53, 255})
+
+	if img, err = Open(untagged); err != nil {
+		t.Fatal(err)
+	}
+	assertPatch(t, "untagged", img, patch)
+
+	if img, err = Open(srgb); err != nil {
+		t.Fatal(err)
+	}
+	assertPatch(t, "srgb primaries", img, patch)
+	if segments := ProfileSegments(adobe); segments != nil {
+		t.Errorf("expected converted profiles not to be embedded again, got %d segments", len(segments))
+	}
+}
+
+func TestResizer_Resize_ConvertsToSRGB(t *testing.T) {
+	contentRoot := t.TempDir()
+	writeProfiledJPEG(t, filepath.Join(contentRoot, "adobe.jpg"), color.NRGBA{200, 120, 60, 255}, matrixProfile(adobeRGBColumns, 563.0/256))
+
+	cachedPath, err := NewResizer(contentRoot, t.TempDir()).Resize("adobe.jpg", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPatch(t, "variant", openImage(t, cachedPath), color.NRGBA{224, 121, 53, 255})
+}
+
+func TestResizer_Resize_EmbedsUnconvertibleProfile(t *testing.T) {
+	contentRoot := t.TempDir()
+	// A lookup-table profile, spread over two segments by its size.
+	profile := iccProfile(map[string][]byte{"A2B0": append([]byte("mft2"), make([]byte, 70000)...)}, []string{"A2B0"})
+	patch := color.NRGBA{200, 120, 60, 255}
+	writeProfiledJPEG(t, filepath.Join(contentRoot, "lut.jpg"), patch, profile)
+
+	cachedPath, err := NewResizer(contentRoot, t.TempDir()).Resize("lut.jpg", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPatch(t, "variant", openImage(t, cachedPath), patch)
+
+	file, err := os.Open(cachedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	segments, err := ReadSegments(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedded := joinICCChunks(segments); !bytes.Equal(embedded, profile) {
+		t.Errorf("expected the %d-byte profile to be embedded in the variant, got %d bytes", len(profile), len(embedded))
+	}
+}
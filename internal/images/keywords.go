@@ -0,0 +1,141 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"os"
+	"strings"
+)
+
+var (
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iptcHeader = []byte("Photoshop 3.0\x00")
+)
+
+const (
+	dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+	iptcResourceID      = 0x0404
+	iptcKeywordsDataset = 25
+)
+
+// IsXMP reports whether the segment is an APP1 XMP packet.
+func (s Segment) IsXMP() bool {
+	return s.Marker == 0xE1 && bytes.HasPrefix(s.Data, xmpHeader)
+}
+
+// IsIPTC reports whether the segment is an APP13 Photoshop block carrying IPTC data.
+func (s Segment) IsIPTC() bool {
+	return s.Marker == 0xED && bytes.HasPrefix(s.Data, iptcHeader)
+}
+
+// ReadSidecarKeywords reads keywords from a Lightroom-style .xmp sidecar file.
+// A missing sidecar is not an error.
+func ReadSidecarKeywords(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseXMPKeywords(content), nil
+}
+
+// parseXMPKeywords returns the dc:subject entries of an XMP packet.
+func parseXMPKeywords(packet []byte) []string {
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	var keywords []string
+	inSubject, inItem := false, false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return keywords
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Space == dublinCoreNamespace && element.Name.Local == "subject" {
+				inSubject = true
+			} else if inSubject && element.Name.Local == "li" {
+				inItem = true
+			}
+		case xml.EndElement:
+			if element.Name.Space == dublinCoreNamespace && element.Name.Local == "subject" {
+				inSubject = false
+			} else if element.Name.Local == "li" {
+				inItem = false
+			}
+		case xml.CharData:
+			if inItem {
+				keywords = append(keywords, string(element))
+			}
+		}
+	}
+}
+
+// parseIPTCKeywords returns the 2:25 keyword datasets from a Photoshop APP13 payload.
+func parseIPTCKeywords(data []byte) []string {
+	var keywords []string
+	resources := data[len(iptcHeader):]
+	for len(resources) >= 12 && bytes.HasPrefix(resources, []byte("8BIM")) {
+		resourceID := binary.BigEndian.Uint16(resources[4:6])
+		// Pascal-string name, padded so length byte plus name is even.
+		nameLength := int(resources[6])
+		offset := 7 + nameLength
+		if offset%2 != 0 {
+			offset++
+		}
+		if offset+4 > len(resources) {
+			break
+		}
+		size := int(binary.BigEndian.Uint32(resources[offset : offset+4]))
+		offset += 4
+		if size < 0 || offset+size > len(resources) {
+			break
+		}
+		if resourceID == iptcResourceID {
+			keywords = append(keywords, parseIPTCRecords(resources[offset:offset+size])...)
+		}
+		offset += size
+		if size%2 != 0 {
+			offset++
+		}
+		if offset > len(resources) {
+			break
+		}
+		resources = resources[offset:]
+	}
+	return keywords
+}
+
+func parseIPTCRecords(records []byte) []string {
+	var keywords []string
+	for len(records) >= 5 && records[0] == 0x1C {
+		record, dataset := records[1], records[2]
+		size := int(binary.BigEndian.Uint16(records[3:5]))
+		// Extended-length datasets are never used for keywords.
+		if size&0x8000 != 0 || 5+size > len(records) {
+			break
+		}
+		if record == 2 && dataset == iptcKeywordsDataset {
+			keywords = append(keywords, string(records[5:5+size]))
+		}
+		records = records[5+size:]
+	}
+	return keywords
+}
+
+// NormalizeKeywords lower-cases and trims keywords, dropping blanks and duplicates.
+func NormalizeKeywords(keywords []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword == "" || seen[keyword] {
+			continue
+		}
+		seen[keyword] = true
+		normalized = append(normalized, keyword)
+	}
+	return normalized
+}
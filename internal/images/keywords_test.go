@@ -0,0 +1,92 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testXMPPacket = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>Bear</rdf:li>
+     <rdf:li>Glacier</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Not a keyword</rdf:li></rdf:Alt></dc:title>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// iptcWithKeywords builds a Photoshop APP13 payload holding IPTC 2:25 keywords.
+func iptcWithKeywords(keywords ...string) []byte {
+	var records bytes.Buffer
+	for _, keyword := range keywords {
+		records.Write([]byte{0x1C, 2, iptcKeywordsDataset})
+		binary.Write(&records, binary.BigEndian, uint16(len(keyword)))
+		records.WriteString(keyword)
+	}
+
+	var payload bytes.Buffer
+	payload.Write(iptcHeader)
+	payload.WriteString("8BIM")
+	binary.Write(&payload, binary.BigEndian, uint16(iptcResourceID))
+	payload.Write([]byte{0, 0})
+	binary.Write(&payload, binary.BigEndian, uint32(records.Len()))
+	payload.Write(records.Bytes())
+	if records.Len()%2 != 0 {
+		payload.WriteByte(0)
+	}
+	return payload.Bytes()
+}
+
+func TestReadMetadata_ReadsXMPAndIPTCKeywords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tagged.jpg")
+	writeJPEGWithSegments(t, path, []Segment{
+		{Marker: 0xE1, Data: append(append([]byte{}, xmpHeader...), testXMPPacket...)},
+		{Marker: 0xED, Data: iptcWithKeywords("glacier", "Portrait ")},
+	})
+
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+
+	expected := []string{"bear", "glacier", "portrait"}
+	if !reflect.DeepEqual(meta.Keywords, expected) {
+		t.Errorf("expected keywords %v, got %v", expected, meta.Keywords)
+	}
+}
+
+func TestReadSidecarKeywords(t *testing.T) {
+	dir := t.TempDir()
+	sidecar := filepath.Join(dir, "DSC0001.xmp")
+	if err := os.WriteFile(sidecar, []byte(testXMPPacket), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keywords, err := ReadSidecarKeywords(sidecar)
+	if err != nil {
+		t.Fatalf("ReadSidecarKeywords failed: %v", err)
+	}
+	if !reflect.DeepEqual(keywords, []string{"Bear", "Glacier"}) {
+		t.Errorf("expected [Bear Glacier], got %v", keywords)
+	}
+
+	missing, err := ReadSidecarKeywords(filepath.Join(dir, "missing.xmp"))
+	if err != nil || missing != nil {
+		t.Errorf("expected no keywords and no error for a missing sidecar, got %v, %v", missing, err)
+	}
+}
+
+func TestNormalizeKeywords(t *testing.T) {
+	got := NormalizeKeywords([]string{" Bear", "bear", "", "GLACIER", "  "})
+	if !reflect.DeepEqual(got, []string{"bear", "glacier"}) {
+		t.Errorf("expected [bear glacier], got %v", got)
+	}
+}
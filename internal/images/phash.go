@@ -0,0 +1,98 @@
+package images
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Hash is a 64-bit perceptual difference hash (dHash). Re-encoded, resized or
+// lightly edited copies of a photo hash to values a few bits apart.
+type Hash uint64
+
+// Distance returns the number of bits that differ between two hashes.
+func (h Hash) Distance(other Hash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+func (h Hash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// PerceptualHash shrinks img to 9x8 greyscale pixels and records, for each row,
+// whether brightness increases from one pixel to the next.
+func PerceptualHash(img image.Image) Hash {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+	var hash Hash
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left < right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashFile decodes the image at path and returns its perceptual hash.
+func HashFile(path string) (Hash, error) {
+	img, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	return PerceptualHash(img), nil
+}
+
+// HashedImage pairs an image path with its perceptual hash.
+type HashedImage struct {
+	Path string
+	Hash Hash
+}
+
+// GroupDuplicates returns groups of images whose hashes are within threshold bits
+// of another image in the group. Groups are sorted by their first path, images
+// within a group by path, and images without a near-duplicate are left out.
+func GroupDuplicates(hashed []HashedImage, threshold int) [][]HashedImage {
+	parent := make([]int, len(hashed))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+	var find func(idx int) int
+	find = func(idx int) int {
+		if parent[idx] != idx {
+			parent[idx] = find(parent[idx])
+		}
+		return parent[idx]
+	}
+
+	for idx := range hashed {
+		for jdx := idx + 1; jdx < len(hashed); jdx++ {
+			if hashed[idx].Hash.Distance(hashed[jdx].Hash) <= threshold {
+				parent[find(jdx)] = find(idx)
+			}
+		}
+	}
+
+	members := make(map[int][]HashedImage)
+	for idx, img := range hashed {
+		root := find(idx)
+		members[root] = append(members[root], img)
+	}
+
+	var groups [][]HashedImage
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(idx, jdx int) bool { return group[idx].Path < group[jdx].Path })
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(idx, jdx int) bool { return groups[idx][0].Path < groups[jdx][0].Path })
+	return groups
+}
@@ -0,0 +1,92 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// createScene draws a photo-like image with smooth gradients and a bright block.
+func createScene(w, h int, mirrored bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := x
+			if mirrored {
+				sx = w - 1 - x
+			}
+			value := uint8((sx*200/w + y*55/h) % 256)
+			if sx > w/3 && sx < w/2 && y > h/4 && y < h/2 {
+				value = 250
+			}
+			img.Set(x, y, color.NRGBA{value, value / 2, 255 - value, 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash_MatchesReencodedCopy(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "DSC01260.png")
+	export := filepath.Join(dir, "DSC01260-export.jpg")
+	if err := imaging.Save(createScene(400, 300, false), original); err != nil {
+		t.Fatal(err)
+	}
+	if err := imaging.Save(imaging.Resize(createScene(400, 300, false), 200, 0, imaging.Lanczos), export, imaging.JPEGQuality(60)); err != nil {
+		t.Fatal(err)
+	}
+
+	originalHash, err := HashFile(original)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+	exportHash, err := HashFile(export)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+	if distance := originalHash.Distance(exportHash); distance > 4 {
+		t.Errorf("expected a re-encoded copy within 4 bits, got %d", distance)
+	}
+
+	different := PerceptualHash(createScene(400, 300, true))
+	if distance := originalHash.Distance(different); distance < 16 {
+		t.Errorf("expected a different photo to be far apart, got %d", distance)
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	hashed := []HashedImage{
+		{Path: "Wildlife/bear.jpg", Hash: 0xFF00},
+		{Path: "Landscape/lake.jpg", Hash: 0x00FF00FF00FF00FF},
+		{Path: "Alaska/bear-2.jpg", Hash: 0xFF01},
+		{Path: "Alaska/bear-3.jpg", Hash: 0xFF03},
+		{Path: "People/portrait.jpg", Hash: 0xF0F0F0F0},
+	}
+
+	groups := GroupDuplicates(hashed, 1)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got %v", groups)
+	}
+	var paths []string
+	for _, img := range groups[0] {
+		paths = append(paths, img.Path)
+	}
+	// bear-3 is two bits from bear.jpg but joins through bear-2.
+	expected := []string{"Alaska/bear-2.jpg", "Alaska/bear-3.jpg", "Wildlife/bear.jpg"}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+	for idx := range expected {
+		if paths[idx] != expected[idx] {
+			t.Errorf("expected %v, got %v", expected, paths)
+			break
+		}
+	}
+
+	if groups := GroupDuplicates(hashed, 0); len(groups) != 0 {
+		t.Errorf("expected no groups at threshold 0, got %v", groups)
+	}
+}
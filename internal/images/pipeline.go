@@ -0,0 +1,128 @@
+package images
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultQuality is the JPEG quality variants are encoded at unless their spec
+// says otherwise.
+const DefaultQuality = 85
+
+// VariantSpec declares one way every image is published.
+type VariantSpec struct {
+	// Width is the variant's maximum width; narrower images are not enlarged.
+	Width int
+	// Aspect, if set, crops the image around its focal point before resizing.
+	Aspect *AspectRatio
+	// Quality is the JPEG quality, DefaultQuality when zero.
+	Quality int
+	// Sharpen is the sigma of the sharpening applied after resizing, which
+	// restores the crispness small variants lose; zero for none.
+	Sharpen float64
+	// Formats lists the modern formats the variant is also published in.
+	Formats []Format
+}
+
+// Suffix is what the variant's file name adds to its source's, e.g. "_w600".
+func (s VariantSpec) Suffix() string {
+	return VariantSuffix(s.Width, s.Aspect)
+}
+
+// JPEGQuality is the quality JPEG copies of the variant are encoded at.
+func (s VariantSpec) JPEGQuality() int {
+	if s.Quality <= 0 {
+		return DefaultQuality
+	}
+	return s.Quality
+}
+
+// Render makes the variant from src. Crops are centred on focus, or on an
+// estimate of it when nil, and the watermark is drawn if it applies.
+func (s VariantSpec) Render(src image.Image, focus *FocalPoint, watermark Watermark) (*image.NRGBA, error) {
+	if s.Aspect != nil {
+		if focus == nil {
+			estimate := EntropyFocus(src)
+			focus = &estimate
+		}
+		src = CropToAspect(src, *s.Aspect, *focus)
+	}
+
+	var dst *image.NRGBA
+	if src.Bounds().Dx() > s.Width {
+		dst = imaging.Resize(src, s.Width, 0, imaging.Lanczos)
+	} else {
+		dst = imaging.Clone(src)
+	}
+	if s.Sharpen > 0 {
+		dst = imaging.Sharpen(dst, s.Sharpen)
+	}
+	if watermark.AppliesTo(s.Width) {
+		return watermark.Apply(dst)
+	}
+	return dst, nil
+}
+
+// Pipeline declares how images are published: an optimized original and the
+// variants made from it. cmd/optimize prebuilds them all, cmd/warmup and the
+// server render the same variants into the cache, and templates link to them,
+// so a variant defined here is one every part of the site agrees on.
+type Pipeline struct {
+	// Original is the optimized copy of the photo itself, never watermarked.
+	Original VariantSpec
+	Variants []VariantSpec
+}
+
+// coverAspect is the shape of the album cards covers are cropped for.
+var coverAspect = AspectRatio{Width: 3, Height: 2}
+
+// DefaultPipeline is how the site publishes photos.
+var DefaultPipeline = Pipeline{
+	Original: VariantSpec{Width: 2500},
+	Variants: []VariantSpec{
+		{Width: 600, Sharpen: 0.5, Formats: ModernFormats}, // grids
+		{Width: 1200, Formats: ModernFormats},              // posts, mid-size screens
+		{Width: 1600, Formats: ModernFormats},              // lightbox
+		{Width: 600, Aspect: &coverAspect, Sharpen: 0.5},   // album cards
+		{Width: 1200, Aspect: &coverAspect},                // album cards on dense screens
+	},
+}
+
+// Variant returns the declared variant of the given width and crop.
+func (p Pipeline) Variant(width int, aspect *AspectRatio) (VariantSpec, bool) {
+	for _, spec := range p.Variants {
+		if spec.Width == width && sameAspect(spec.Aspect, aspect) {
+			return spec, true
+		}
+	}
+	return VariantSpec{}, false
+}
+
+// Spec returns the declared variant of the given width and crop, or a plain
+// one for variants the pipeline does not declare.
+func (p Pipeline) Spec(width int, aspect *AspectRatio) VariantSpec {
+	if spec, ok := p.Variant(width, aspect); ok {
+		return spec
+	}
+	return VariantSpec{Width: width, Aspect: aspect}
+}
+
+// Ladder gathers the widths of the variants cropped to aspect, or uncropped
+// when aspect is nil, for srcset attributes.
+func (p Pipeline) Ladder(aspect *AspectRatio) Ladder {
+	ladder := Ladder{Aspect: aspect}
+	for _, spec := range p.Variants {
+		if sameAspect(spec.Aspect, aspect) {
+			ladder.Widths = append(ladder.Widths, spec.Width)
+		}
+	}
+	return ladder
+}
+
+func sameAspect(a, b *AspectRatio) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,76 @@
+package images
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPipeline_Variant(t *testing.T) {
+	square := AspectRatio{Width: 1, Height: 1}
+	for _, tt := range []struct {
+		width  int
+		aspect *AspectRatio
+		ok     bool
+	}{
+		{600, nil, true},
+		{1600, nil, true},
+		{1200, &AspectRatio{Width: 3, Height: 2}, true},
+		{1600, &coverAspect, false},
+		{600, &square, false},
+		{800, nil, false},
+	} {
+		spec, ok := DefaultPipeline.Variant(tt.width, tt.aspect)
+		if ok != tt.ok {
+			t.Errorf("Variant(%d, %v): expected ok %v", tt.width, tt.aspect, tt.ok)
+			continue
+		}
+		if ok && (spec.Width != tt.width || !sameAspect(spec.Aspect, tt.aspect)) {
+			t.Errorf("Variant(%d, %v) returned %+v", tt.width, tt.aspect, spec)
+		}
+	}
+
+	if spec := DefaultPipeline.Spec(800, nil); spec.Width != 800 || spec.Sharpen != 0 || spec.Formats != nil {
+		t.Errorf("expected a plain spec for an undeclared width, got %+v", spec)
+	}
+	if spec := DefaultPipeline.Spec(600, nil); spec.Sharpen == 0 || len(spec.Formats) == 0 {
+		t.Errorf("expected the declared spec, got %+v", spec)
+	}
+}
+
+func TestPipeline_Ladder(t *testing.T) {
+	pipeline := Pipeline{Variants: []VariantSpec{
+		{Width: 400},
+		{Width: 400, Aspect: &coverAspect},
+		{Width: 800},
+	}}
+	if got := pipeline.Ladder(nil).Widths; len(got) != 2 || got[0] != 400 || got[1] != 800 {
+		t.Errorf("expected uncropped widths [400 800], got %v", got)
+	}
+	if got := pipeline.Ladder(&AspectRatio{Width: 3, Height: 2}).Widths; len(got) != 1 || got[0] != 400 {
+		t.Errorf("expected cropped widths [400], got %v", got)
+	}
+}
+
+func TestVariantSpec_Render(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+
+	small, err := VariantSpec{Width: 600}.Render(src, nil, Watermark{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Bounds().Dx() != 300 {
+		t.Errorf("expected narrower images not to be enlarged, got width %d", small.Bounds().Dx())
+	}
+
+	cover, err := VariantSpec{Width: 150, Aspect: &coverAspect, Sharpen: 0.5}.Render(src, &FocalPoint{X: 0.5, Y: 0.5}, Watermark{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover.Bounds().Dx() != 150 || cover.Bounds().Dy() != 100 {
+		t.Errorf("expected a 150x100 crop, got %v", cover.Bounds().Size())
+	}
+
+	if (VariantSpec{}).JPEGQuality() != DefaultQuality || (VariantSpec{Quality: 70}).JPEGQuality() != 70 {
+		t.Error("expected quality to default to DefaultQuality")
+	}
+}
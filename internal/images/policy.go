@@ -0,0 +1,361 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"sort"
+)
+
+// GPSMode controls what a published image reveals about where it was taken.
+type GPSMode int
+
+const (
+	// GPSStrip drops the location entirely. It is the zero value so that a
+	// policy nobody configured never publishes coordinates.
+	GPSStrip GPSMode = iota
+	// GPSCoarse publishes the location rounded by GeoPoint.Coarsen.
+	GPSCoarse
+	// GPSExact publishes the recorded latitude and longitude.
+	GPSExact
+)
+
+// MetadataPolicy decides which embedded metadata survives into a published image.
+// EXIF is whitelisted: only DefaultExifFields, the fields named in Keep and the
+// location allowed by GPS are written. XMP and IPTC are reduced to keywords.
+// Serial numbers, owner names and maker notes are never published.
+type MetadataPolicy struct {
+	GPS  GPSMode
+	Keep []string
+}
+
+// ForVariant returns the policy for resized variants, which never carry a location.
+func (p MetadataPolicy) ForVariant() MetadataPolicy {
+	p.GPS = GPSStrip
+	return p
+}
+
+// DefaultExifFields are the EXIF fields every published image keeps: the ones
+// the site reads for ordering and exposure details.
+var DefaultExifFields = []string{
+	"Make", "Model", "Orientation", "DateTime",
+	"ExposureTime", "FNumber", "ISO", "DateTimeOriginal", "FocalLength", "LensModel",
+}
+
+// publishableIFD0Fields and publishableExifFields are every field a policy may
+// keep, by IFD. Anything missing here is always stripped.
+var publishableIFD0Fields = map[string]uint16{
+	"ImageDescription": 0x010E,
+	"Make":             tagMake,
+	"Model":            tagModel,
+	"Orientation":      tagOrientation,
+	"Software":         0x0131,
+	"DateTime":         tagDateTime,
+	"Artist":           0x013B,
+	"Copyright":        0x8298,
+}
+
+var publishableExifFields = map[string]uint16{
+	"ExposureTime":          tagExposureTime,
+	"FNumber":               tagFNumber,
+	"ExposureProgram":       0x8822,
+	"ISO":                   tagISO,
+	"DateTimeOriginal":      tagDateTimeOriginal,
+	"DateTimeDigitized":     0x9004,
+	"ExposureBiasValue":     0x9204,
+	"MeteringMode":          0x9207,
+	"Flash":                 0x9209,
+	"FocalLength":           tagFocalLength,
+	"FocalLengthIn35mmFilm": 0xA405,
+	"LensMake":              0xA433,
+	"LensModel":             tagLensModel,
+}
+
+// allowedTags returns the whitelisted tags of one IFD.
+func (p MetadataPolicy) allowedTags(fields map[string]uint16) map[uint16]bool {
+	allowed := make(map[uint16]bool)
+	for _, names := range [][]string{DefaultExifFields, p.Keep} {
+		for _, name := range names {
+			if tag, ok := fields[name]; ok {
+				allowed[tag] = true
+			}
+		}
+	}
+	return allowed
+}
+
+// FilterSegments returns the metadata segments to publish in place of the given
+// ones: a rebuilt EXIF block and an XMP packet holding only keywords.
+func (p MetadataPolicy) FilterSegments(segments []Segment) []Segment {
+	var filtered []Segment
+	var keywords []string
+	for _, segment := range segments {
+		switch {
+		case segment.IsExif():
+			if exif := p.filterExif(segment.Data[len(exifHeader):]); exif != nil {
+				filtered = append(filtered, Segment{Marker: 0xE1, Data: append(append([]byte{}, exifHeader...), exif...)})
+			}
+		case segment.IsXMP():
+			keywords = append(keywords, parseXMPKeywords(segment.Data[len(xmpHeader):])...)
+		case segment.IsIPTC():
+			keywords = append(keywords, parseIPTCKeywords(segment.Data)...)
+		}
+	}
+	if keywords = NormalizeKeywords(keywords); len(keywords) > 0 {
+		filtered = append(filtered, Segment{Marker: 0xE1, Data: append(append([]byte{}, xmpHeader...), KeywordsXMP(keywords)...)})
+	}
+	return filtered
+}
+
+// RewriteMetadata returns the JPEG or PNG image in data with its metadata
+// filtered by the policy. Other formats are returned unchanged.
+func (p MetadataPolicy) RewriteMetadata(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return p.rewriteJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNGMetadata(data), nil
+	}
+	return data, nil
+}
+
+// rewriteJPEG keeps the segments that describe how to decode the image (JFIF,
+// ICC profiles, Adobe markers, tables) and replaces the metadata segments with
+// FilterSegments' output. Comments and other application segments are dropped.
+func (p MetadataPolicy) rewriteJPEG(data []byte) ([]byte, error) {
+	segments, err := ReadSegments(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	body.Write(data[:2])
+	offset := 2
+	for offset+4 <= len(data) && data[offset] == 0xFF {
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		end := offset + 2 + int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+		if end > len(data) {
+			break
+		}
+		if keepSegment(marker) {
+			body.Write(data[offset:end])
+		}
+		offset = end
+	}
+	body.Write(data[offset:])
+
+	var out bytes.Buffer
+	if err := InsertSegments(&out, body.Bytes(), p.FilterSegments(segments)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// keepSegment reports whether a marker before the image data carries no private metadata.
+func keepSegment(marker byte) bool {
+	switch {
+	case marker == 0xE0, marker == 0xE2, marker == 0xEE:
+		return true
+	case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE:
+		return false
+	}
+	return true
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are the PNG chunks that may carry EXIF, XMP or free text.
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNGMetadata drops every metadata chunk from a PNG. PNGs carry no keywords
+// the site reads, so nothing is kept.
+func stripPNGMetadata(data []byte) []byte {
+	out := append([]byte{}, data[:len(pngSignature)]...)
+	offset := len(pngSignature)
+	for offset+12 <= len(data) {
+		end := offset + 12 + int(binary.BigEndian.Uint32(data[offset:offset+4]))
+		if end > len(data) || end < offset {
+			break
+		}
+		if !pngMetadataChunks[string(data[offset+4:offset+8])] {
+			out = append(out, data[offset:end]...)
+		}
+		offset = end
+	}
+	return append(out, data[offset:]...)
+}
+
+// WriteJPEG encodes img as a JPEG with the given metadata segments. img is
+// taken to be upright, as Open decodes it, so any EXIF orientation among the
+// segments is reset to normal.
+func WriteJPEG(w io.Writer, img image.Image, quality int, segments []Segment) error {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	return InsertSegments(w, encoded.Bytes(), uprightSegments(segments))
+}
+
+// KeywordsXMP returns a minimal XMP packet listing keywords as dc:subject.
+func KeywordsXMP(keywords []string) []byte {
+	var packet bytes.Buffer
+	packet.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`)
+	packet.WriteString(`<rdf:Description rdf:about="" xmlns:dc="` + dublinCoreNamespace + `"><dc:subject><rdf:Bag>`)
+	for _, keyword := range keywords {
+		packet.WriteString("<rdf:li>")
+		xml.EscapeText(&packet, []byte(keyword))
+		packet.WriteString("</rdf:li>")
+	}
+	packet.WriteString(`</rdf:Bag></dc:subject></rdf:Description></rdf:RDF></x:xmpmeta>`)
+	return packet.Bytes()
+}
+
+// filterExif rebuilds a TIFF block holding only the fields the policy allows,
+// or returns nil when none survive.
+func (p MetadataPolicy) filterExif(data []byte) []byte {
+	reader, ifd0Offset, ok := newTIFFReader(data)
+	if !ok {
+		return nil
+	}
+
+	ifd0 := reader.readIFD(ifd0Offset)
+	kept := keepEntries(ifd0, p.allowedTags(publishableIFD0Fields))
+
+	var exifEntries []tiffEntry
+	if offset, ok := reader.uint32Value(ifd0[tagExifIFD]); ok {
+		exifEntries = keepEntries(reader.readIFD(offset), p.allowedTags(publishableExifFields))
+	}
+
+	var gpsEntries []tiffEntry
+	if offset, ok := reader.uint32Value(ifd0[tagGPSIFD]); ok && p.GPS != GPSStrip {
+		if location := parseGPS(reader, reader.readIFD(offset)); location != nil {
+			if p.GPS == GPSCoarse {
+				*location = location.Coarsen()
+			}
+			gpsEntries = gpsIFDEntries(reader.order, *location)
+		}
+	}
+
+	if len(kept) == 0 && len(exifEntries) == 0 && len(gpsEntries) == 0 {
+		return nil
+	}
+	return encodeTIFF(reader.order, kept, exifEntries, gpsEntries)
+}
+
+func keepEntries(ifd map[uint16]tiffEntry, allowed map[uint16]bool) []tiffEntry {
+	var kept []tiffEntry
+	for tag, entry := range ifd {
+		if allowed[tag] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// gpsIFDEntries encodes a location as GPS latitude and longitude tags.
+func gpsIFDEntries(order byteOrder, location GeoPoint) []tiffEntry {
+	latitudeRef, longitudeRef := "N", "E"
+	if location.Latitude < 0 {
+		latitudeRef = "S"
+	}
+	if location.Longitude < 0 {
+		longitudeRef = "W"
+	}
+	return []tiffEntry{
+		{tag: tagGPSLatitudeRef, kind: 2, count: 2, valueRaw: []byte(latitudeRef + "\x00")},
+		{tag: tagGPSLatitude, kind: 5, count: 3, valueRaw: degreesRaw(order, math.Abs(location.Latitude))},
+		{tag: tagGPSLongitudeRef, kind: 2, count: 2, valueRaw: []byte(longitudeRef + "\x00")},
+		{tag: tagGPSLongitude, kind: 5, count: 3, valueRaw: degreesRaw(order, math.Abs(location.Longitude))},
+	}
+}
+
+// degreesRaw encodes decimal degrees as degrees, minutes and hundredths of seconds.
+func degreesRaw(order byteOrder, value float64) []byte {
+	degrees := math.Floor(value)
+	minutes := math.Floor((value - degrees) * 60)
+	seconds := math.Round(((value-degrees)*60 - minutes) * 60 * 100)
+	var raw []byte
+	for _, part := range [][2]uint32{{uint32(degrees), 1}, {uint32(minutes), 1}, {uint32(seconds), 100}} {
+		raw = order.AppendUint32(raw, part[0])
+		raw = order.AppendUint32(raw, part[1])
+	}
+	return raw
+}
+
+// encodeTIFF lays out IFD0 followed by the Exif and GPS sub-IFDs (when present)
+// and the out-of-line values they reference.
+func encodeTIFF(order byteOrder, ifd0, exifIFD, gpsIFD []tiffEntry) []byte {
+	ifdSize := func(entries []tiffEntry) int { return 2 + 12*len(entries) + 4 }
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, kind: 4, count: 1})
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagGPSIFD, kind: 4, count: 1})
+	}
+
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset
+	if len(exifIFD) > 0 {
+		gpsOffset += ifdSize(exifIFD)
+	}
+	dataOffset := gpsOffset
+	if len(gpsIFD) > 0 {
+		dataOffset += ifdSize(gpsIFD)
+	}
+
+	var values bytes.Buffer
+	writeIFD := func(out *bytes.Buffer, entries []tiffEntry) {
+		sort.Slice(entries, func(idx, jdx int) bool { return entries[idx].tag < entries[jdx].tag })
+		out.Write(order.AppendUint16(nil, uint16(len(entries))))
+		for _, entry := range entries {
+			value := entry.valueRaw
+			switch entry.tag {
+			case tagExifIFD:
+				value = order.AppendUint32(nil, uint32(exifOffset))
+			case tagGPSIFD:
+				value = order.AppendUint32(nil, uint32(gpsOffset))
+			}
+			raw := order.AppendUint16(nil, entry.tag)
+			raw = order.AppendUint16(raw, entry.kind)
+			raw = order.AppendUint32(raw, entry.count)
+			if len(value) <= 4 {
+				raw = append(raw, value...)
+				raw = append(raw, make([]byte, 4-len(value))...)
+			} else {
+				raw = order.AppendUint32(raw, uint32(dataOffset+values.Len()))
+				values.Write(value)
+				// Values start on word boundaries.
+				if values.Len()%2 != 0 {
+					values.WriteByte(0)
+				}
+			}
+			out.Write(raw)
+		}
+		out.Write(order.AppendUint32(nil, 0))
+	}
+
+	var tiff bytes.Buffer
+	if order == binary.BigEndian {
+		tiff.WriteString("MM")
+	} else {
+		tiff.WriteString("II")
+	}
+	tiff.Write(order.AppendUint16(nil, 42))
+	tiff.Write(order.AppendUint32(nil, 8))
+	writeIFD(&tiff, ifd0)
+	if len(exifIFD) > 0 {
+		writeIFD(&tiff, exifIFD)
+	}
+	if len(gpsIFD) > 0 {
+		writeIFD(&tiff, gpsIFD)
+	}
+	tiff.Write(values.Bytes())
+	return tiff.Bytes()
+}
@@ -0,0 +1,221 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sensitiveExif carries a location, an owner-identifying serial number and an
+// optional Artist field alongside the fields the site reads.
+func sensitiveExif() []byte {
+	return buildExifWithGPS(
+		[]testTIFFEntry{asciiEntry(tagMake, "SONY"), asciiEntry(tagModel, "ILCE-7M3"), asciiEntry(0x013B, "Merl Martin")},
+		[]testTIFFEntry{
+			asciiEntry(tagDateTimeOriginal, "2018:06:23 10:00:00"),
+			rationalEntry(tagFNumber, 28, 10),
+			asciiEntry(0xA431, "3301234"),
+		},
+		[]testTIFFEntry{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			degreesEntry(tagGPSLatitude, 61, 13, 12),
+			asciiEntry(tagGPSLongitudeRef, "W"),
+			degreesEntry(tagGPSLongitude, 149, 53, 24),
+		},
+	)
+}
+
+func filteredMetadata(t *testing.T, policy MetadataPolicy, segments []Segment) (Metadata, []Segment) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "published.jpg")
+	filtered := policy.FilterSegments(segments)
+	writeJPEGWithSegments(t, path, filtered)
+	meta, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	return meta, filtered
+}
+
+func TestMetadataPolicy_StripsSerialNumbersAndKeepsDefaults(t *testing.T) {
+	meta, filtered := filteredMetadata(t, MetadataPolicy{GPS: GPSExact}, []Segment{{Marker: 0xE1, Data: sensitiveExif()}})
+
+	if bytes.Contains(filtered[0].Data, []byte("3301234")) {
+		t.Error("expected the body serial number to be stripped")
+	}
+	if bytes.Contains(filtered[0].Data, []byte("Merl Martin")) {
+		t.Error("expected Artist to be stripped unless kept")
+	}
+	if meta.Exposure.Model != "ILCE-7M3" || meta.Exposure.FNumber != 2.8 {
+		t.Errorf("expected default exposure fields to survive, got %+v", meta.Exposure)
+	}
+	if !meta.CaptureTime.Equal(time.Date(2018, 6, 23, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected capture time to survive, got %v", meta.CaptureTime)
+	}
+	if meta.Location == nil || meta.Location.Latitude < 61.21 || meta.Location.Latitude > 61.23 {
+		t.Errorf("expected the exact location to survive, got %v", meta.Location)
+	}
+}
+
+func TestMetadataPolicy_KeepsRequestedFields(t *testing.T) {
+	_, filtered := filteredMetadata(t, MetadataPolicy{Keep: []string{"Artist", "BodySerialNumber"}}, []Segment{{Marker: 0xE1, Data: sensitiveExif()}})
+
+	if !bytes.Contains(filtered[0].Data, []byte("Merl Martin")) {
+		t.Error("expected Artist to be kept")
+	}
+	if bytes.Contains(filtered[0].Data, []byte("3301234")) {
+		t.Error("expected serial numbers to be stripped even when requested")
+	}
+}
+
+func TestMetadataPolicy_CoarsensAndStripsLocation(t *testing.T) {
+	segments := []Segment{{Marker: 0xE1, Data: sensitiveExif()}}
+
+	meta, _ := filteredMetadata(t, MetadataPolicy{GPS: GPSCoarse}, segments)
+	if meta.Location == nil || meta.Location.Coarsen() != (GeoPoint{Latitude: 61.2, Longitude: -149.9}) {
+		t.Errorf("expected coarse location 61.2,-149.9, got %v", meta.Location)
+	}
+	if d := meta.Location.Latitude - 61.2; d > 1e-6 || d < -1e-6 {
+		t.Errorf("expected the published latitude itself to be coarse, got %v", meta.Location.Latitude)
+	}
+
+	meta, _ = filteredMetadata(t, MetadataPolicy{}, segments)
+	if meta.Location != nil {
+		t.Errorf("expected no location, got %v", *meta.Location)
+	}
+}
+
+func TestMetadataPolicy_ReducesXMPAndIPTCToKeywords(t *testing.T) {
+	xmp := strings.Replace(testXMPPacket, "<dc:subject>", `<exif:GPSLatitude xmlns:exif="http://ns.adobe.com/exif/1.0/">61,13.2N</exif:GPSLatitude><dc:subject>`, 1)
+	meta, filtered := filteredMetadata(t, MetadataPolicy{GPS: GPSExact}, []Segment{
+		{Marker: 0xE1, Data: append(append([]byte{}, xmpHeader...), xmp...)},
+		{Marker: 0xED, Data: iptcWithKeywords("River")},
+	})
+
+	if len(filtered) != 1 || !filtered[0].IsXMP() {
+		t.Fatalf("expected a single XMP segment, got %d segments", len(filtered))
+	}
+	if bytes.Contains(filtered[0].Data, []byte("GPSLatitude")) {
+		t.Error("expected XMP location fields to be stripped")
+	}
+	if !reflect.DeepEqual(meta.Keywords, []string{"bear", "glacier", "river"}) {
+		t.Errorf("expected keywords to survive, got %v", meta.Keywords)
+	}
+}
+
+func TestWriteJPEG_VariantsCarryNoGPS(t *testing.T) {
+	dir := t.TempDir()
+	segments := []Segment{{Marker: 0xE1, Data: sensitiveExif()}}
+	policy := MetadataPolicy{GPS: GPSExact}
+
+	for _, suffix := range []string{"_w600", "_w1600"} {
+		path := filepath.Join(dir, "photo"+suffix+".jpg")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = WriteJPEG(file, image.NewRGBA(image.Rect(0, 0, 8, 8)), 85, policy.ForVariant().FilterSegments(segments))
+		file.Close()
+		if err != nil {
+			t.Fatalf("WriteJPEG failed: %v", err)
+		}
+
+		meta, err := ReadMetadata(path)
+		if err != nil {
+			t.Fatalf("ReadMetadata failed: %v", err)
+		}
+		if meta.Location != nil {
+			t.Errorf("%s: expected no GPS, got %v", suffix, *meta.Location)
+		}
+		if hasGPSIFD(t, path) {
+			t.Errorf("%s: expected no GPS IFD", suffix)
+		}
+		if meta.Exposure.Model != "ILCE-7M3" {
+			t.Errorf("%s: expected exposure fields to survive, got %+v", suffix, meta.Exposure)
+		}
+	}
+}
+
+func hasGPSIFD(t *testing.T, path string) bool {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	segments, err := ReadSegments(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, segment := range segments {
+		if !segment.IsExif() {
+			continue
+		}
+		reader, offset, ok := newTIFFReader(segment.Data[len(exifHeader):])
+		if !ok {
+			continue
+		}
+		if _, ok := reader.readIFD(offset)[tagGPSIFD]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRewriteMetadata_JPEGKeepsImageAndDropsComments(t *testing.T) {
+	var original bytes.Buffer
+	comment := Segment{Marker: 0xFE, Data: []byte("shot at home")}
+	icc := Segment{Marker: 0xE2, Data: []byte("ICC_PROFILE\x00test")}
+	if err := WriteJPEG(&original, image.NewRGBA(image.Rect(0, 0, 8, 8)), 85, []Segment{{Marker: 0xE1, Data: sensitiveExif()}, icc, comment}); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err := MetadataPolicy{}.RewriteMetadata(original.Bytes())
+	if err != nil {
+		t.Fatalf("RewriteMetadata failed: %v", err)
+	}
+	if bytes.Contains(published, []byte("shot at home")) {
+		t.Error("expected comments to be stripped")
+	}
+	if !bytes.Contains(published, []byte("ICC_PROFILE")) {
+		t.Error("expected the ICC profile to be kept")
+	}
+	if bytes.Contains(published, []byte("3301234")) {
+		t.Error("expected serial numbers to be stripped")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(published)); err != nil {
+		t.Errorf("expected a decodable JPEG, got %v", err)
+	}
+}
+
+func TestRewriteMetadata_PNGDropsTextChunks(t *testing.T) {
+	var original bytes.Buffer
+	if err := png.Encode(&original, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	// Insert a tEXt chunk after IHDR (signature + 25-byte IHDR chunk).
+	text := []byte("tEXtLocation\x0061.22,-149.89")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	data := append(append(append([]byte{}, original.Bytes()[:33]...), chunk...), original.Bytes()[33:]...)
+
+	published, err := MetadataPolicy{GPS: GPSExact}.RewriteMetadata(data)
+	if err != nil {
+		t.Fatalf("RewriteMetadata failed: %v", err)
+	}
+	if bytes.Contains(published, []byte("149.89")) {
+		t.Error("expected PNG text chunks to be stripped")
+	}
+	if !bytes.Equal(published, original.Bytes()) {
+		t.Error("expected the remaining chunks to be unchanged")
+	}
+}
@@ -2,27 +2,122 @@ package images
 
 import (
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
-	"strconv"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/disintegration/imaging"
 )
 
 type Resizer struct {
-	contentRoot string
-	cacheRoot   string
+	contentRoot  string
+	cacheRoot    string
+	pipeline     Pipeline
+	watermarkFor WatermarkFunc
+	focusFor     FocusFunc
+	// decodes holds a slot for each original being decoded, bounding the memory
+	// a burst of uncached requests can take.
+	decodes chan struct{}
+	open    func(path string) (image.Image, error)
+	cache   *Cache
+
+	mu       sync.Mutex
+	inFlight map[string]*render
+}
+
+// render is a variant being written. Requests made meanwhile wait for it
+// instead of decoding the original again.
+type render struct {
+	done chan struct{}
+	err  error
+}
+
+// WatermarkFunc returns the watermark for an image, by path relative to the content root.
+type WatermarkFunc func(relPath string) (Watermark, error)
+
+// ResizerOption configures optional parts of a Resizer.
+type ResizerOption func(*Resizer)
+
+// WithWatermarks draws each image's watermark over variants at least
+// WatermarkMinWidth wide.
+func WithWatermarks(watermarkFor WatermarkFunc) ResizerOption {
+	return func(r *Resizer) {
+		r.watermarkFor = watermarkFor
+	}
+}
+
+// FocusFunc returns the focal point set for an image, by path relative to the
+// content root, or nil when it should be estimated from the image.
+type FocusFunc func(relPath string) (*FocalPoint, error)
+
+// WithFocalPoints centres crops on the focal points focusFor returns.
+func WithFocalPoints(focusFor FocusFunc) ResizerOption {
+	return func(r *Resizer) {
+		r.focusFor = focusFor
+	}
+}
+
+// WithPipeline renders variants as pipeline declares them rather than as
+// DefaultPipeline does.
+func WithPipeline(pipeline Pipeline) ResizerOption {
+	return func(r *Resizer) {
+		r.pipeline = pipeline
+	}
+}
+
+// WithMaxConcurrentDecodes limits how many originals are decoded at once. It
+// defaults to the number of CPUs.
+func WithMaxConcurrentDecodes(n int) ResizerOption {
+	return func(r *Resizer) {
+		if n > 0 {
+			r.decodes = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithCache records reads and writes of variants in cache, which keeps the
+// cache root within its budget. Without it the cache root grows unchecked.
+func WithCache(cache *Cache) ResizerOption {
+	return func(r *Resizer) {
+		r.cache = cache
+	}
 }
 
-func NewResizer(contentRoot, cacheRoot string) *Resizer {
-	return &Resizer{
+func NewResizer(contentRoot, cacheRoot string, opts ...ResizerOption) *Resizer {
+	r := &Resizer{
 		contentRoot: contentRoot,
 		cacheRoot:   cacheRoot,
+		pipeline:    DefaultPipeline,
+		decodes:     make(chan struct{}, runtime.NumCPU()),
+		open:        Open,
+		inFlight:    map[string]*render{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
+// Resize returns the pipeline's variant of the given width.
 func (r *Resizer) Resize(relPath string, width int) (string, error) {
+	return r.Render(relPath, r.pipeline.Spec(width, nil))
+}
+
+// Crop returns the pipeline's variant of the given width cropped to the aspect
+// ratio around the image's focal point.
+func (r *Resizer) Crop(relPath string, width int, aspect AspectRatio) (string, error) {
+	return r.Render(relPath, r.pipeline.Spec(width, &aspect))
+}
+
+// Render writes the variant to the cache unless an up-to-date copy is there,
+// and returns its path. Cached names spell out everything the variant depends
+// on besides the source and the spec.
+func (r *Resizer) Render(relPath string, spec VariantSpec) (string, error) {
+	width, aspect := spec.Width, spec.Aspect
+
 	fullPath := filepath.Join(r.contentRoot, relPath)
 
 	srcInfo, err := os.Stat(fullPath)
@@ -35,43 +130,164 @@ func (r *Resizer) Resize(relPath string, width int) (string, error) {
 	ext := filepath.Ext(filename)
 	nameWithoutExt := strings.TrimSuffix(filename, ext)
 
-	cachedFilename := fmt.Sprintf("%s_w%d%s", nameWithoutExt, width, ext)
-	cachedDir := filepath.Join(r.cacheRoot, dir)
-	cachedPath := filepath.Join(cachedDir, cachedFilename)
+	var watermark Watermark
+	if r.watermarkFor != nil {
+		if watermark, err = r.watermarkFor(relPath); err != nil {
+			return "", fmt.Errorf("failed to load watermark: %w", err)
+		}
+	}
+
+	var focus *FocalPoint
+	if aspect != nil && r.focusFor != nil {
+		if focus, err = r.focusFor(relPath); err != nil {
+			return "", fmt.Errorf("failed to load focal point: %w", err)
+		}
+	}
+
+	variant := VariantSuffix(width, aspect)
+	if aspect != nil && focus != nil {
+		variant += fmt.Sprintf("_f%.0f-%.0f", focus.X*1000, focus.Y*1000)
+	}
+	if watermark.AppliesTo(width) {
+		// The key changes with the watermark, so edits never serve stale variants.
+		key, err := watermark.Key()
+		if err != nil {
+			return "", err
+		}
+		variant += "_wm" + key
+	}
+	cachedPath := filepath.Join(r.cacheRoot, dir, nameWithoutExt+variant+ext)
 
-	cachedInfo, cacheErr := os.Stat(cachedPath)
-	if cacheErr == nil && cachedInfo.ModTime().After(srcInfo.ModTime()) {
+	if isFresh(cachedPath, srcInfo) {
+		if r.cache != nil {
+			r.cache.Touch(cachedPath)
+		}
 		return cachedPath, nil
 	}
 
-	// Ensure cache dir exists
-	if err := os.MkdirAll(cachedDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create cache dir: %w", err)
+	err = r.coalesce(cachedPath, func() error {
+		// An earlier request may have written it since we looked.
+		if isFresh(cachedPath, srcInfo) {
+			return nil
+		}
+		if err := r.write(fullPath, cachedPath, spec, focus, watermark); err != nil {
+			return err
+		}
+		if r.cache != nil {
+			return r.cache.Add(cachedPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cachedPath, nil
+}
+
+// EncodeSibling returns the path of the copy in format of the cached variant at
+// path, encoding it unless an up-to-date copy is there. Like renders, requests
+// for the same copy share one encode, and each encode takes a decode slot.
+func (r *Resizer) EncodeSibling(path string, format Format) (string, error) {
+	srcInfo, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	sibling := format.Sibling(path)
+	if isFresh(sibling, srcInfo) {
+		if r.cache != nil {
+			r.cache.Touch(sibling)
+		}
+		return sibling, nil
+	}
+
+	err = r.coalesce(sibling, func() error {
+		r.decodes <- struct{}{}
+		defer func() { <-r.decodes }()
+		if _, err := format.EncodeSibling(path); err != nil {
+			return err
+		}
+		if r.cache != nil {
+			return r.cache.Add(sibling)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return sibling, nil
+}
+
+// isFresh reports whether the cached file exists and is newer than the source.
+func isFresh(cachedPath string, srcInfo os.FileInfo) bool {
+	cachedInfo, err := os.Stat(cachedPath)
+	return err == nil && cachedInfo.ModTime().After(srcInfo.ModTime())
+}
+
+// coalesce runs fn for key unless it is already running, in which case it
+// waits for that run and shares its result.
+func (r *Resizer) coalesce(key string, fn func() error) error {
+	r.mu.Lock()
+	if running, ok := r.inFlight[key]; ok {
+		r.mu.Unlock()
+		<-running.done
+		return running.err
 	}
+	current := &render{done: make(chan struct{})}
+	r.inFlight[key] = current
+	r.mu.Unlock()
+
+	current.err = fn()
+
+	r.mu.Lock()
+	delete(r.inFlight, key)
+	r.mu.Unlock()
+	close(current.done)
+	return current.err
+}
 
-	// Resize and save
-	srcImage, err := imaging.Open(fullPath)
+// write decodes the original at fullPath and saves the variant to cachedPath.
+func (r *Resizer) write(fullPath, cachedPath string, spec VariantSpec, focus *FocalPoint, watermark Watermark) error {
+	cachedDir := filepath.Dir(cachedPath)
+	if err := os.MkdirAll(cachedDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	format, err := imaging.FormatFromFilename(cachedPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open image: %w", err)
+		return err
 	}
 
-	// Resize to width, preserving aspect ratio (height = 0)
-	dstImage := imaging.Resize(srcImage, width, 0, imaging.Lanczos)
+	r.decodes <- struct{}{}
+	defer func() { <-r.decodes }()
 
-	// Atomic write: save to temp file first, then rename.
-	// This prevents race conditions where concurrent requests could
-	// corrupt the cache file by writing to it simultaneously.
-	cachedExt := filepath.Ext(cachedPath)
-	cachedBase := strings.TrimSuffix(cachedPath, cachedExt)
-	tmpPath := cachedBase + ".tmp." + strconv.Itoa(os.Getpid()) + cachedExt
-	if err := imaging.Save(dstImage, tmpPath); err != nil {
-		os.Remove(tmpPath)
-		return "", fmt.Errorf("failed to save image: %w", err)
+	srcImage, err := r.open(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
 	}
-	if err := os.Rename(tmpPath, cachedPath); err != nil {
-		os.Remove(tmpPath)
-		return "", fmt.Errorf("failed to move cache file: %w", err)
+	dstImage, err := spec.Render(srcImage, focus, watermark)
+	if err != nil {
+		return err
 	}
 
-	return cachedPath, nil
+	// Atomic write: save to a uniquely named temp file first, then rename,
+	// so readers never see a partial image.
+	tmp, err := os.CreateTemp(cachedDir, "."+filepath.Base(cachedPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if format == imaging.JPEG {
+		err = WriteJPEG(tmp, dstImage, spec.JPEGQuality(), ProfileSegments(fullPath))
+	} else {
+		err = imaging.Encode(tmp, dstImage, format)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), cachedPath); err != nil {
+		return fmt.Errorf("failed to move cache file: %w", err)
+	}
+	return nil
 }
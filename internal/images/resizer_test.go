@@ -6,7 +6,12 @@ import (
 	"image/png"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestResizer_Resize(t *testing.T) {
@@ -66,3 +71,208 @@ func createDummyImage(t *testing.T, path string, w, h int) {
 		t.Fatal(err)
 	}
 }
+
+func TestResizer_Resize_Watermark(t *testing.T) {
+	contentRoot := t.TempDir()
+	createDummyImage(t, filepath.Join(contentRoot, "photo.png"), 2000, 1000)
+
+	watermark := Watermark{Text: "© Merl Martin"}
+	resizer := NewResizer(contentRoot, t.TempDir(), WithWatermarks(func(relPath string) (Watermark, error) {
+		return watermark, nil
+	}))
+
+	small, err := resizer.Resize("photo.png", 600)
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if filepath.Base(small) != "photo_w600.png" {
+		t.Errorf("expected grid variants not to be watermarked; got %s", small)
+	}
+
+	large, err := resizer.Resize("photo.png", 1600)
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	plain := NewResizer(contentRoot, t.TempDir())
+	unmarked, err := plain.Resize("photo.png", 1600)
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if filepath.Base(large) == filepath.Base(unmarked) {
+		t.Errorf("expected the watermark in the cache key; got %s", large)
+	}
+	if !changedIn(openImage(t, unmarked), openImage(t, large), image.Rect(800, 400, 1600, 800)) {
+		t.Error("expected the large variant to carry the watermark")
+	}
+
+	watermark.Position = WatermarkTopLeft
+	moved, err := resizer.Resize("photo.png", 1600)
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if moved == large {
+		t.Error("expected changed watermark settings to give a new cached variant")
+	}
+}
+
+func openImage(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestResizer_Crop(t *testing.T) {
+	contentRoot := t.TempDir()
+	createDummyImage(t, filepath.Join(contentRoot, "photo.png"), 1200, 1200)
+
+	focus := &FocalPoint{X: 0.5, Y: 0}
+	resizer := NewResizer(contentRoot, t.TempDir(), WithFocalPoints(func(relPath string) (*FocalPoint, error) {
+		return focus, nil
+	}))
+
+	cropped, err := resizer.Crop("photo.png", 600, AspectRatio{3, 2})
+	if err != nil {
+		t.Fatalf("Crop failed: %v", err)
+	}
+	if bounds := openImage(t, cropped).Bounds(); bounds.Dx() != 600 || bounds.Dy() != 400 {
+		t.Errorf("expected a 600x400 crop; got %v", bounds)
+	}
+
+	focus = &FocalPoint{X: 0.5, Y: 1}
+	moved, err := resizer.Crop("photo.png", 600, AspectRatio{3, 2})
+	if err != nil {
+		t.Fatalf("Crop failed: %v", err)
+	}
+	if moved == cropped {
+		t.Error("expected a new focal point to give a new cached variant")
+	}
+
+	focus = nil
+	estimated, err := resizer.Crop("photo.png", 600, AspectRatio{1, 1})
+	if err != nil {
+		t.Fatalf("Crop failed: %v", err)
+	}
+	if filepath.Base(estimated) != "photo_w600_1x1.png" {
+		t.Errorf("unexpected cached name %s", filepath.Base(estimated))
+	}
+}
+
+// slowOpen wraps the resizer's decoder to count decodes, track how many run at
+// once and hold each one open long enough for requests to overlap.
+func slowOpen(r *Resizer, decodes, running, peak *atomic.Int32) {
+	open := r.open
+	r.open = func(path string) (image.Image, error) {
+		decodes.Add(1)
+		now := running.Add(1)
+		defer running.Add(-1)
+		for {
+			previous := peak.Load()
+			if now <= previous || peak.CompareAndSwap(previous, now) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return open(path)
+	}
+}
+
+func TestResizer_Resize_Concurrent(t *testing.T) {
+	contentRoot := t.TempDir()
+	cacheRoot := t.TempDir()
+	createDummyImage(t, filepath.Join(contentRoot, "test_image.png"), 100, 100)
+
+	resizer := NewResizer(contentRoot, cacheRoot, WithMaxConcurrentDecodes(2))
+	var decodes, running, peak atomic.Int32
+	slowOpen(resizer, &decodes, &running, &peak)
+
+	// Ten requests for each of four widths.
+	widths := []int{20, 30, 40, 50}
+	paths := make([][]string, len(widths))
+	var wg sync.WaitGroup
+	for i, width := range widths {
+		paths[i] = make([]string, 10)
+		for j := range paths[i] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cachedPath, err := resizer.Resize("test_image.png", width)
+				if err != nil {
+					t.Errorf("Resize(%d) failed: %v", width, err)
+				}
+				paths[i][j] = cachedPath
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := decodes.Load(); got != int32(len(widths)) {
+		t.Errorf("expected one decode per width; got %d", got)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 decodes at once; got %d", got)
+	}
+	for i, width := range widths {
+		for _, cachedPath := range paths[i] {
+			if cachedPath != paths[i][0] {
+				t.Errorf("expected every request for width %d to share %s; got %s", width, paths[i][0], cachedPath)
+			}
+		}
+		if bounds := openImage(t, paths[i][0]).Bounds(); bounds.Dx() != width {
+			t.Errorf("expected width %d; got %d", width, bounds.Dx())
+		}
+	}
+
+	entries, err := os.ReadDir(cacheRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(widths) {
+		t.Errorf("expected only the %d variants in the cache, with no temp files left; got %d entries", len(widths), len(entries))
+	}
+}
+
+func TestResizer_EncodeSibling_Concurrent(t *testing.T) {
+	cacheRoot := t.TempDir()
+	log := filepath.Join(t.TempDir(), "encodes.log")
+	// A stand-in encoder that logs when it runs and copies its input.
+	slow := Format{Name: "slow", encoder: "sh", args: func(src, dst string) []string {
+		return []string{"-c", `echo start >> "$2"; sleep 0.05; echo end >> "$2"; cp "$0" "$1"`, src, dst, log}
+	}}
+	var variants []string
+	for _, name := range []string{"a_w600.jpg", "b_w600.jpg"} {
+		variants = append(variants, filepath.Join(cacheRoot, name))
+		createDummyImage(t, variants[len(variants)-1], 10, 10)
+	}
+
+	resizer := NewResizer(t.TempDir(), cacheRoot, WithMaxConcurrentDecodes(1))
+	var wg sync.WaitGroup
+	for range 5 {
+		for _, variant := range variants {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if sibling, err := resizer.EncodeSibling(variant, slow); err != nil || sibling != slow.Sibling(variant) {
+					t.Errorf("EncodeSibling(%s) = %s, %v", variant, sibling, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One encode per variant, never two at once.
+	if got := strings.Fields(string(data)); !reflect.DeepEqual(got, []string{"start", "end", "start", "end"}) {
+		t.Errorf("expected two encodes one after the other; got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrNotJPEG is returned by ReadSegments when the input is not a JPEG stream.
+var ErrNotJPEG = errors.New("not a JPEG file")
+
+// Segment is a JPEG application segment (APPn marker plus payload).
+type Segment struct {
+	Marker byte
+	Data   []byte
+}
+
+// IsExif reports whether the segment is an APP1 EXIF block.
+func (s Segment) IsExif() bool {
+	return s.Marker == 0xE1 && bytes.HasPrefix(s.Data, exifHeader)
+}
+
+// ReadSegments returns the APPn segments that precede the image data in a JPEG stream.
+func ReadSegments(r io.Reader) ([]Segment, error) {
+	reader := bufio.NewReader(r)
+
+	var soi [2]byte
+	if _, err := io.ReadFull(reader, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, ErrNotJPEG
+	}
+
+	var segments []Segment
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(reader, header[:2]); err != nil {
+			return segments, nil
+		}
+		if header[0] != 0xFF {
+			return segments, nil
+		}
+		marker := header[1]
+		// Start of scan or end of image: no more metadata follows.
+		if marker == 0xDA || marker == 0xD9 {
+			return segments, nil
+		}
+		if _, err := io.ReadFull(reader, header[2:4]); err != nil {
+			return segments, nil
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4])) - 2
+		if length < 0 {
+			return segments, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return segments, nil
+		}
+		if marker >= 0xE0 && marker <= 0xEF {
+			segments = append(segments, Segment{Marker: marker, Data: payload})
+		}
+	}
+}
+
+// InsertSegments writes the JPEG in encoded with the given segments placed
+// directly after its start-of-image marker.
+func InsertSegments(w io.Writer, encoded []byte, segments []Segment) error {
+	if len(encoded) < 2 || encoded[0] != 0xFF || encoded[1] != 0xD8 {
+		return ErrNotJPEG
+	}
+	if _, err := w.Write(encoded[:2]); err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if len(segment.Data)+2 > 0xFFFF {
+			continue
+		}
+		header := []byte{0xFF, segment.Marker, 0, 0}
+		binary.BigEndian.PutUint16(header[2:], uint16(len(segment.Data)+2))
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(segment.Data); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(encoded[2:])
+	return err
+}
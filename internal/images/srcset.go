@@ -0,0 +1,180 @@
+package images
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Ladder is a set of variants of an image: the widths it is published at,
+// narrowest first, and the aspect ratio they are cropped to, if any.
+type Ladder struct {
+	Widths []int
+	Aspect *AspectRatio
+	// fingerprint returns the Fingerprint of the variant at a URL, or "" when
+	// it is unknown; see Fingerprinted.
+	fingerprint func(url string) string
+}
+
+var (
+	// FullLadder holds the uncropped variants shown in grids, lightboxes and posts.
+	FullLadder = DefaultPipeline.Ladder(nil)
+	// CoverLadder holds the crops shown on album cards, which are never full width.
+	CoverLadder = DefaultPipeline.Ladder(&coverAspect)
+)
+
+// VariantSuffix is what a variant's file name adds to its source's, e.g.
+// "_w600" or "_w600_3x2" when cropped to aspect.
+func VariantSuffix(width int, aspect *AspectRatio) string {
+	suffix := fmt.Sprintf("_w%d", width)
+	if aspect != nil {
+		suffix += fmt.Sprintf("_%dx%d", aspect.Width, aspect.Height)
+	}
+	return suffix
+}
+
+// variantName matches the names of variants, e.g. "DSC01_w600.jpg" or
+// "DSC01_w600_3x2.jpg", capturing the source name, width, aspect and extension.
+var variantName = regexp.MustCompile(`^(.+)_w(\d+)(?:_(\d+)x(\d+))?(\.[^.]+)$`)
+
+// IsVariantName reports whether name is that of a variant rather than a photo.
+func IsVariantName(name string) bool {
+	return variantName.MatchString(name)
+}
+
+// ParseVariantName splits a variant's file name into its source's name, width
+// and aspect ratio, which is nil for uncropped variants.
+func ParseVariantName(name string) (source string, width int, aspect *AspectRatio, ok bool) {
+	match := variantName.FindStringSubmatch(name)
+	if match == nil {
+		return "", 0, nil, false
+	}
+	width, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, nil, false
+	}
+	if match[3] != "" {
+		ratio, err := ParseAspectRatio(match[3] + ":" + match[4])
+		if err != nil {
+			return "", 0, nil, false
+		}
+		aspect = &ratio
+	}
+	return match[1] + match[5], width, aspect, true
+}
+
+// Variant returns the URL of the variant of the image at path+ext at width,
+// where path has no extension, e.g. "/assets/portfolio/Alaska/DSC01".
+func (l Ladder) Variant(path, ext string, width int) string {
+	return path + VariantSuffix(width, l.Aspect) + ext
+}
+
+// Fingerprinted returns the ladder with the URLs of its variants in srcsets
+// fingerprinted, e.g. "/assets/portfolio/Alaska/DSC01_w600.jpg?v=1f2e3d4c5b6a7988",
+// so browsers may cache them for good. fingerprint returns the Fingerprint of
+// the variant at a URL, or "" to leave the URL as it is.
+func (l Ladder) Fingerprinted(fingerprint func(url string) string) Ladder {
+	l.fingerprint = fingerprint
+	return l
+}
+
+// versioned returns the URL of the variant at width, fingerprinted if known.
+func (l Ladder) versioned(path, ext string, width int) string {
+	variant := l.Variant(path, ext, width)
+	if l.fingerprint == nil {
+		return variant
+	}
+	if hash := l.fingerprint(variant); hash != "" {
+		return variant + "?v=" + hash
+	}
+	return variant
+}
+
+// Only returns the ladder cut down to the given widths, e.g. those an image's
+// variants have actually been published at.
+func (l Ladder) Only(widths []int) Ladder {
+	only := Ladder{Aspect: l.Aspect, fingerprint: l.fingerprint}
+	for _, width := range l.Widths {
+		if slices.Contains(widths, width) {
+			only.Widths = append(only.Widths, width)
+		}
+	}
+	return only
+}
+
+// Srcset lists the ladder's variants of the image for a srcset attribute.
+func (l Ladder) Srcset(path, ext string) string {
+	candidates := make([]string, len(l.Widths))
+	for i, width := range l.Widths {
+		candidates[i] = fmt.Sprintf("%s %dw", l.versioned(path, ext, width), width)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// Layout is how wide an image is shown, as a sizes attribute, and the width
+// of the variant browsers that ignore srcset are given.
+type Layout struct {
+	Sizes string
+	Width int
+}
+
+var (
+	// GridLayout is for thumbnail rows of two or three photos on wide screens.
+	GridLayout = Layout{Sizes: "(min-width: 768px) 50vw, 100vw", Width: 600}
+	// CardLayout is for album cards, three to a row on wide screens.
+	CardLayout = Layout{Sizes: "(min-width: 768px) 33vw, 100vw", Width: 600}
+	// ProseLayout is for photos in the blog's text column.
+	ProseLayout = Layout{Sizes: "(min-width: 768px) 768px, 100vw", Width: 1200}
+	// FullLayout is for photos that fill the screen.
+	FullLayout = Layout{Sizes: "100vw", Width: 1600}
+)
+
+// Source is a <picture> source offering the variants in a modern format.
+type Source struct {
+	Type   string `json:"type"`
+	SrcSet string `json:"srcset"`
+}
+
+// Responsive holds the attributes of an <img> the browser picks a width for,
+// and the sources to put ahead of it in a <picture>.
+type Responsive struct {
+	Src     string   `json:"src"`
+	SrcSet  string   `json:"srcset"`
+	Sizes   string   `json:"sizes"`
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// src returns the URL browsers that ignore srcset are given: the narrowest
+// variant at least width wide, the widest one, or the image itself when the
+// ladder is empty.
+func (l Ladder) src(path, ext string, width int) string {
+	if len(l.Widths) == 0 {
+		return path + ext
+	}
+	for _, candidate := range l.Widths {
+		if candidate >= width {
+			return l.versioned(path, ext, candidate)
+		}
+	}
+	return l.versioned(path, ext, l.Widths[len(l.Widths)-1])
+}
+
+// Responsive describes the image at path+ext laid out as layout. formats names
+// the ModernFormats its variants are also published in.
+func (l Ladder) Responsive(path, ext string, layout Layout, formats []string) Responsive {
+	responsive := Responsive{
+		Src:    l.src(path, ext, layout.Width),
+		SrcSet: l.Srcset(path, ext),
+		Sizes:  layout.Sizes,
+	}
+	for _, format := range ModernFormats {
+		for _, name := range formats {
+			if name == format.Name {
+				responsive.Sources = append(responsive.Sources, Source{Type: format.MIMEType, SrcSet: l.Srcset(path, "."+format.Name)})
+			}
+		}
+	}
+	return responsive
+}
@@ -0,0 +1,107 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLadder_Responsive(t *testing.T) {
+	got := FullLadder.Responsive("/assets/portfolio/Alaska/DSC01", ".jpg", GridLayout, []string{"webp", "unknown"})
+	want := Responsive{
+		Src:    "/assets/portfolio/Alaska/DSC01_w600.jpg",
+		SrcSet: "/assets/portfolio/Alaska/DSC01_w600.jpg 600w, /assets/portfolio/Alaska/DSC01_w1200.jpg 1200w, /assets/portfolio/Alaska/DSC01_w1600.jpg 1600w",
+		Sizes:  GridLayout.Sizes,
+		Sources: []Source{{
+			Type:   "image/webp",
+			SrcSet: "/assets/portfolio/Alaska/DSC01_w600.webp 600w, /assets/portfolio/Alaska/DSC01_w1200.webp 1200w, /assets/portfolio/Alaska/DSC01_w1600.webp 1600w",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	cover := CoverLadder.Responsive("/assets/portfolio/Alaska/DSC01", ".jpg", CardLayout, nil)
+	if cover.Src != "/assets/portfolio/Alaska/DSC01_w600_3x2.jpg" || cover.SrcSet != "/assets/portfolio/Alaska/DSC01_w600_3x2.jpg 600w, /assets/portfolio/Alaska/DSC01_w1200_3x2.jpg 1200w" {
+		t.Errorf("expected cropped cover variants, got %+v", cover)
+	}
+}
+
+func TestLadder_Fingerprinted(t *testing.T) {
+	hashes := map[string]string{
+		"/a/b_w600.jpg":  "1f2e3d4c5b6a7988",
+		"/a/b_w600.webp": "0123456789abcdef",
+	}
+	ladder := FullLadder.Fingerprinted(func(url string) string { return hashes[url] }).Only([]int{600, 1200})
+	got := ladder.Responsive("/a/b", ".jpg", GridLayout, []string{"webp"})
+	if got.Src != "/a/b_w600.jpg?v=1f2e3d4c5b6a7988" {
+		t.Errorf("expected a fingerprinted src, got %q", got.Src)
+	}
+	if got.SrcSet != "/a/b_w600.jpg?v=1f2e3d4c5b6a7988 600w, /a/b_w1200.jpg 1200w" {
+		t.Errorf("expected unknown variants to be left as they are, got %q", got.SrcSet)
+	}
+	if len(got.Sources) != 1 || got.Sources[0].SrcSet != "/a/b_w600.webp?v=0123456789abcdef 600w, /a/b_w1200.webp 1200w" {
+		t.Errorf("expected each format to carry its own fingerprint, got %+v", got.Sources)
+	}
+}
+
+func TestLadder_Only(t *testing.T) {
+	only := CoverLadder.Only([]int{1200, 1600})
+	if !reflect.DeepEqual(only.Widths, []int{1200}) || only.Aspect != CoverLadder.Aspect {
+		t.Errorf("expected the cropped 1200 width alone, got %+v", only)
+	}
+
+	for _, tt := range []struct {
+		widths []int
+		layout Layout
+		src    string
+	}{
+		{[]int{600, 1600}, ProseLayout, "/a/b_w1600.jpg"},
+		{[]int{600}, FullLayout, "/a/b_w600.jpg"},
+		{nil, GridLayout, "/a/b.jpg"},
+	} {
+		if got := FullLadder.Only(tt.widths).Responsive("/a/b", ".jpg", tt.layout, nil); got.Src != tt.src {
+			t.Errorf("widths %v as %q: expected src %s, got %s", tt.widths, tt.layout.Sizes, tt.src, got.Src)
+		}
+	}
+}
+
+func TestLayouts_UsePublishedWidths(t *testing.T) {
+	for _, tt := range []struct {
+		ladder Ladder
+		layout Layout
+	}{
+		{FullLadder, GridLayout},
+		{FullLadder, ProseLayout},
+		{FullLadder, FullLayout},
+		{CoverLadder, CardLayout},
+	} {
+		found := false
+		for _, width := range tt.ladder.Widths {
+			found = found || width == tt.layout.Width
+		}
+		if !found {
+			t.Errorf("layout %q falls back to width %d, which its ladder %v does not publish", tt.layout.Sizes, tt.layout.Width, tt.ladder.Widths)
+		}
+	}
+}
+
+func TestParseVariantName(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		width  int
+		aspect *AspectRatio
+		ok     bool
+	}{
+		{"DSC01_w600.jpg", "DSC01.jpg", 600, nil, true},
+		{"DSC01_w1200_3x2.webp", "DSC01.webp", 1200, &AspectRatio{Width: 3, Height: 2}, true},
+		{"DSC01.jpg", "", 0, nil, false},
+		{"DSC01_w600_0x2.jpg", "", 0, nil, false},
+	}
+	for _, tt := range tests {
+		source, width, aspect, ok := ParseVariantName(tt.name)
+		if source != tt.source || width != tt.width || !reflect.DeepEqual(aspect, tt.aspect) || ok != tt.ok {
+			t.Errorf("ParseVariantName(%q) = %q, %d, %v, %v", tt.name, source, width, aspect, ok)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// WatermarkMinWidth is the narrowest variant that is watermarked; grid
+// thumbnails are too small to be worth lifting.
+const WatermarkMinWidth = 1600
+
+// Watermark positions. The mark is inset from the edges it is anchored to.
+const (
+	WatermarkBottomRight = "bottom-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkTopLeft     = "top-left"
+	WatermarkCenter      = "center"
+)
+
+// Watermark is a text or PNG mark drawn over large variants. Image is a file
+// path and takes precedence over Text. Position defaults to bottom-right and
+// Opacity, between 0 and 1, to 0.5.
+type Watermark struct {
+	Text     string
+	Image    string
+	Position string
+	Opacity  float64
+}
+
+// Enabled reports whether there is anything to draw.
+func (w Watermark) Enabled() bool {
+	return w.Text != "" || w.Image != ""
+}
+
+// AppliesTo reports whether a variant of the given width carries the watermark.
+func (w Watermark) AppliesTo(width int) bool {
+	return w.Enabled() && width >= WatermarkMinWidth
+}
+
+// Key identifies the watermark's settings and, for image marks, the mark file's
+// version, so cached variants can be told apart when either changes.
+func (w Watermark) Key() (string, error) {
+	version := ""
+	if w.Image != "" {
+		info, err := os.Stat(w.Image)
+		if err != nil {
+			return "", fmt.Errorf("watermark image: %w", err)
+		}
+		version = fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n%s\n%g", w.Text, w.Image, version, w.Position, w.opacity())))
+	return hex.EncodeToString(sum[:4]), nil
+}
+
+// Apply returns img with the watermark drawn over it.
+func (w Watermark) Apply(img image.Image) (*image.NRGBA, error) {
+	width := img.Bounds().Dx()
+	var mark image.Image
+	if w.Image != "" {
+		var err error
+		if mark, err = imaging.Open(w.Image); err != nil {
+			return nil, fmt.Errorf("watermark image: %w", err)
+		}
+		// Keep image marks to a fifth of the photo's width.
+		if mark.Bounds().Dx() > width/5 {
+			mark = imaging.Resize(mark, width/5, 0, imaging.Lanczos)
+		}
+	} else {
+		mark = textMark(w.Text, width/40)
+	}
+
+	dst := imaging.Clone(img)
+	return imaging.Overlay(dst, mark, w.offset(dst.Bounds(), mark.Bounds(), width/50), w.opacity()), nil
+}
+
+func (w Watermark) opacity() float64 {
+	if w.Opacity <= 0 || w.Opacity > 1 {
+		return 0.5
+	}
+	return w.Opacity
+}
+
+// offset is where the mark's top-left corner goes, margin pixels in from the
+// edges it is anchored to.
+func (w Watermark) offset(bounds, mark image.Rectangle, margin int) image.Point {
+	left := bounds.Min.X + margin
+	right := bounds.Max.X - mark.Dx() - margin
+	top := bounds.Min.Y + margin
+	bottom := bounds.Max.Y - mark.Dy() - margin
+	switch w.Position {
+	case WatermarkBottomLeft:
+		return image.Pt(left, bottom)
+	case WatermarkTopRight:
+		return image.Pt(right, top)
+	case WatermarkTopLeft:
+		return image.Pt(left, top)
+	case WatermarkCenter:
+		return image.Pt((bounds.Min.X+bounds.Max.X-mark.Dx())/2, (bounds.Min.Y+bounds.Max.Y-mark.Dy())/2)
+	}
+	return image.Pt(right, bottom)
+}
+
+// textMark renders text in white over a dark shadow, scaled so that lines are
+// about height pixels tall.
+func textMark(text string, height int) image.Image {
+	face := basicfont.Face7x13
+	metrics := face.Metrics()
+	textWidth := font.MeasureString(face, text).Ceil()
+	lineHeight := metrics.Height.Ceil()
+	mark := image.NewNRGBA(image.Rect(0, 0, textWidth+1, lineHeight+1))
+
+	for _, layer := range []struct {
+		shift int
+		color color.Color
+	}{{1, color.Black}, {0, color.White}} {
+		drawer := font.Drawer{
+			Dst:  mark,
+			Src:  image.NewUniform(layer.color),
+			Face: face,
+			Dot:  fixed.P(layer.shift, metrics.Ascent.Ceil()+layer.shift),
+		}
+		drawer.DrawString(text)
+	}
+
+	if height <= lineHeight {
+		return mark
+	}
+	return imaging.Resize(mark, 0, height, imaging.Linear)
+}
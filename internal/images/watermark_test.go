@@ -0,0 +1,102 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func solidImage(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// changedIn reports whether any pixel of the region differs between a and b.
+func changedIn(a, b image.Image, region image.Rectangle) bool {
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestWatermark_ApplyText(t *testing.T) {
+	src := solidImage(1600, 1000, color.NRGBA{40, 80, 120, 255})
+
+	tests := []struct {
+		position string
+		marked   image.Rectangle
+		clean    image.Rectangle
+	}{
+		{"", image.Rect(800, 500, 1600, 1000), image.Rect(0, 0, 800, 500)},
+		{WatermarkTopLeft, image.Rect(0, 0, 800, 500), image.Rect(800, 500, 1600, 1000)},
+		{WatermarkCenter, image.Rect(600, 400, 1000, 600), image.Rect(0, 0, 400, 1000)},
+	}
+	for _, tt := range tests {
+		marked, err := Watermark{Text: "© Merl Martin", Position: tt.position}.Apply(src)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if marked.Bounds() != src.Bounds() {
+			t.Errorf("%q: expected the size to be kept; got %v", tt.position, marked.Bounds())
+		}
+		if !changedIn(src, marked, tt.marked) || changedIn(src, marked, tt.clean) {
+			t.Errorf("%q: expected the mark only in %v", tt.position, tt.marked)
+		}
+	}
+}
+
+func TestWatermark_ApplyImage(t *testing.T) {
+	markPath := filepath.Join(t.TempDir(), "mark.png")
+	createDummyImage(t, markPath, 1000, 200)
+	src := solidImage(1600, 1000, color.NRGBA{0, 0, 255, 255})
+
+	marked, err := Watermark{Image: markPath, Position: WatermarkBottomLeft, Opacity: 1}.Apply(src)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	// The mark is scaled to a fifth of the width and inset by a fiftieth.
+	if r, _, b, _ := marked.At(40, 1000-40-1).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("expected the opaque red mark in the bottom-left corner; got %v", marked.At(40, 959))
+	}
+	if changedIn(src, marked, image.Rect(40+320+1, 0, 1600, 1000)) {
+		t.Error("expected the mark to be at most a fifth of the width")
+	}
+
+	if _, err := (Watermark{Image: filepath.Join(t.TempDir(), "missing.png")}).Apply(src); err == nil {
+		t.Error("expected a missing mark image to fail")
+	}
+}
+
+func TestWatermark_Key(t *testing.T) {
+	base := Watermark{Text: "© Merl Martin"}
+	key, err := base.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, changed := range []Watermark{
+		{Text: "© Someone Else"},
+		{Text: base.Text, Position: WatermarkTopLeft},
+		{Text: base.Text, Opacity: 0.9},
+	} {
+		if other, _ := changed.Key(); other == key {
+			t.Errorf("%+v: expected a different key", changed)
+		}
+	}
+	if same, _ := (Watermark{Text: base.Text, Opacity: 0.5}).Key(); same != key {
+		t.Error("expected the default opacity to give the same key")
+	}
+
+	if !base.AppliesTo(1600) || base.AppliesTo(600) || (Watermark{}).AppliesTo(1600) {
+		t.Error("expected watermarks only on enabled variants at least 1600 wide")
+	}
+}
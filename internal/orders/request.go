@@ -0,0 +1,51 @@
+// Package orders records the print order requests visitors send from photo pages.
+package orders
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxQuantity caps how many prints of one photo a single request may ask for.
+const MaxQuantity = 10
+
+// Request is a visitor's request for prints of one photo. Nothing is charged;
+// the photographer follows up by email.
+type Request struct {
+	ID       string    `json:"id"`
+	Received time.Time `json:"received"`
+	Photo    string    `json:"photo"` // permalink of the photo, e.g. "/portfolio/Alaska/denali"
+	Size     string    `json:"size"`
+	Quantity int       `json:"quantity"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Validate checks a request for a photo offered in the given sizes. It returns
+// a message per invalid form field, or nil when the request is valid.
+func (r Request) Validate(sizes []string) map[string]string {
+	problems := map[string]string{}
+	if !slices.Contains(sizes, r.Size) {
+		problems["size"] = "Choose one of the available sizes."
+	}
+	if r.Quantity < 1 || r.Quantity > MaxQuantity {
+		problems["quantity"] = fmt.Sprintf("Quantity must be between 1 and %d.", MaxQuantity)
+	}
+	if name := strings.TrimSpace(r.Name); name == "" || len(name) > 100 {
+		problems["name"] = "Enter your name."
+	}
+	if address, err := mail.ParseAddress(r.Email); err != nil || address.Address != r.Email || len(r.Email) > 254 {
+		problems["email"] = "Enter a valid email address."
+	}
+	if len(r.Message) > 2000 {
+		problems["message"] = "Keep the message under 2000 characters."
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
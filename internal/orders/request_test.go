@@ -0,0 +1,30 @@
+package orders
+
+import "testing"
+
+func TestRequest_Validate(t *testing.T) {
+	sizes := []string{"8x10", "12x18"}
+	valid := Request{Size: "8x10", Quantity: 2, Name: "Ada", Email: "ada@example.com"}
+	if problems := valid.Validate(sizes); problems != nil {
+		t.Fatalf("expected a valid request; got %v", problems)
+	}
+
+	tests := []struct {
+		field  string
+		modify func(*Request)
+	}{
+		{"size", func(r *Request) { r.Size = "40x60" }},
+		{"quantity", func(r *Request) { r.Quantity = 0 }},
+		{"quantity", func(r *Request) { r.Quantity = MaxQuantity + 1 }},
+		{"name", func(r *Request) { r.Name = "   " }},
+		{"email", func(r *Request) { r.Email = "ada" }},
+		{"email", func(r *Request) { r.Email = "Ada <ada@example.com>" }},
+	}
+	for _, tt := range tests {
+		request := valid
+		tt.modify(&request)
+		if problems := request.Validate(sizes); problems[tt.field] == "" {
+			t.Errorf("%+v: expected a problem with %s; got %v", request, tt.field, problems)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package orders
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	// Add records a request, filling in its ID and received time.
+	Add(request Request) (Request, error)
+	// List returns every recorded request, newest first.
+	List() ([]Request, error)
+}
+
+// fileStore appends requests to a JSON Lines file. Lines are never rewritten,
+// so a crash can at worst lose the request being written.
+type fileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Add(request Request) (Request, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Request{}, err
+	}
+	request.ID = hex.EncodeToString(id)
+	request.Received = time.Now().UTC()
+
+	line, err := json.Marshal(request)
+	if err != nil {
+		return Request{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return Request{}, err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return Request{}, err
+	}
+	if torn, err := endsMidLine(file); err != nil || torn {
+		// Start a fresh line so the torn one cannot swallow this request.
+		line = append([]byte{'\n'}, line...)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return Request{}, err
+	}
+	return request, file.Close()
+}
+
+// endsMidLine reports whether the file's last line lacks its newline.
+func endsMidLine(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+func (s *fileStore) List() ([]Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requests []Request
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var request Request
+		// Skip a line cut short by a crash rather than hide every other request.
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(requests)
+	return requests, nil
+}
@@ -0,0 +1,45 @@
+package orders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore_AppendsAndListsNewestFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "orders.jsonl")
+	store := NewFileStore(path)
+
+	if requests, err := store.List(); err != nil || len(requests) != 0 {
+		t.Fatalf("expected no requests before the first order; got %v, %v", requests, err)
+	}
+
+	first, err := store.Add(Request{Photo: "/portfolio/Alaska/denali", Size: "8x10", Quantity: 1, Name: "Ada", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if first.ID == "" || first.Received.IsZero() {
+		t.Errorf("expected an ID and received time; got %+v", first)
+	}
+	if _, err := store.Add(Request{Photo: "/portfolio/Alaska/river", Size: "12x18", Quantity: 2, Name: "Grace", Email: "grace@example.com"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// A torn final line must not hide the requests before it.
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	file.WriteString(`{"id":"torn`)
+	file.Close()
+
+	store = NewFileStore(path)
+	if _, err := store.Add(Request{Photo: "/portfolio/Alaska/lake", Size: "8x10", Quantity: 1, Name: "Alan", Email: "alan@example.com"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	requests, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(requests) != 3 || requests[0].Photo != "/portfolio/Alaska/lake" || requests[1].Photo != "/portfolio/Alaska/river" || requests[2].ID != first.ID {
+		t.Errorf("expected every whole request newest first; got %+v", requests)
+	}
+}
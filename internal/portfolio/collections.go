@@ -0,0 +1,158 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// Collection is a curated set of photos drawn from any number of categories,
+// defined by a YAML manifest in the collections directory.
+type Collection struct {
+	Name        string
+	Title       string
+	Description string
+	Images      []Image
+	CoverImage  Image
+}
+
+type collectionManifest struct {
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description"`
+	Cover       string   `yaml:"cover"`
+	Images      []string `yaml:"images"`
+}
+
+const collectionManifestExt = ".yaml"
+
+// Option configures optional parts of the filesystem service.
+type Option func(*filesystemService)
+
+// WithCollections sets the directory holding collection manifests.
+func WithCollections(dir string) Option {
+	return func(s *filesystemService) {
+		s.collectionsRoot = dir
+	}
+}
+
+func (s *filesystemService) GetCollections() ([]Collection, error) {
+	if s.collectionsRoot == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(s.collectionsRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == collectionManifestExt {
+			names = append(names, strings.TrimSuffix(entry.Name(), collectionManifestExt))
+		}
+	}
+	sort.Strings(names)
+
+	var collections []Collection
+	for _, name := range names {
+		collection, err := s.GetCollection(name)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+func (s *filesystemService) GetCollection(name string) (Collection, error) {
+	if s.collectionsRoot == "" || name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return Collection{}, ErrCollectionNotFound
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.collectionsRoot, name+collectionManifestExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Collection{}, ErrCollectionNotFound
+		}
+		return Collection{}, err
+	}
+
+	var manifest collectionManifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return Collection{}, fmt.Errorf("collection %s: %w", name, err)
+	}
+
+	collection := Collection{
+		Name:        name,
+		Title:       manifest.Title,
+		Description: manifest.Description,
+	}
+	if collection.Title == "" {
+		collection.Title = name
+	}
+
+	for _, ref := range manifest.Images {
+		// A photo deleted or made private since the manifest was written is
+		// left out rather than taking the collection, and every page listing
+		// collections, down with it.
+		img, err := s.resolveImage(ref)
+		if err != nil {
+			log.Printf("collection %s: skipping %v", name, err)
+			continue
+		}
+		collection.Images = append(collection.Images, img)
+		if ref == manifest.Cover {
+			collection.CoverImage = img
+		}
+	}
+	if collection.CoverImage.Path == "" && len(collection.Images) > 0 {
+		collection.CoverImage = collection.Images[0]
+	}
+
+	return collection, nil
+}
+
+// resolveImage turns a manifest reference such as "Alaska/2018/DSC06126.jpg"
+// into an Image, checking that it stays within and exists under the portfolio root.
+func (s *filesystemService) resolveImage(ref string) (Image, error) {
+	segments, ok := splitAlbumPath(ref)
+	if !ok || len(segments) < 2 {
+		return Image{}, fmt.Errorf("invalid image path %q", ref)
+	}
+
+	fileName := segments[len(segments)-1]
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return Image{}, fmt.Errorf("unsupported image %q", ref)
+	}
+
+	relPath := filepath.Join(segments...)
+	if _, err := os.Stat(filepath.Join(s.root, relPath)); err != nil {
+		return Image{}, fmt.Errorf("image %q: %w", ref, err)
+	}
+
+	album, err := s.loadAlbumContext(segments[:len(segments)-1])
+	if err != nil {
+		return Image{}, err
+	}
+	if album.settings.Access.IsPrivate() {
+		return Image{}, fmt.Errorf("image %q is in private album %s", ref, album.settings.Access.Album)
+	}
+	entries, err := os.ReadDir(album.dirPath)
+	if err != nil {
+		return Image{}, err
+	}
+	album.files = fileNames(entries)
+	return s.readImage(album, fileName, ext)
+}
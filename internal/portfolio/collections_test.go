@@ -0,0 +1,177 @@
+package portfolio
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupCollectionsFixture(t *testing.T) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"Landscape", "Wildlife", "Alaska/2018"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTempFile(t, filepath.Join(root, "Landscape", "mountains.jpg"))
+	createTempFile(t, filepath.Join(root, "Wildlife", "bear.jpg"))
+	createTempFile(t, filepath.Join(root, "Alaska", "2018", "glacier.png"))
+
+	collectionsDir := t.TempDir()
+	return root, collectionsDir
+}
+
+func writeManifest(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilesystemService_GetCollection_ResolvesImagesAcrossCategories(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+	writeManifest(t, collectionsDir, "best-of-2024", `
+title: Best of 2024
+description: Favourites from the year.
+cover: Wildlife/bear.jpg
+images:
+  - Landscape/mountains.jpg
+  - Wildlife/bear.jpg
+  - Alaska/2018/glacier.png
+`)
+
+	svc := NewFilesystemService(root, "/assets/portfolio", WithCollections(collectionsDir))
+
+	collection, err := svc.GetCollection("best-of-2024")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if collection.Title != "Best of 2024" || collection.Description != "Favourites from the year." {
+		t.Errorf("unexpected title/description: %q / %q", collection.Title, collection.Description)
+	}
+
+	expected := []Image{
+		{Path: filepath.Join("/assets/portfolio", "Landscape", "mountains"), Ext: ".jpg"},
+		{Path: filepath.Join("/assets/portfolio", "Wildlife", "bear"), Ext: ".jpg"},
+		{Path: filepath.Join("/assets/portfolio", "Alaska", "2018", "glacier"), Ext: ".png"},
+	}
+	if len(collection.Images) != len(expected) {
+		t.Fatalf("expected %d images, got %d", len(expected), len(collection.Images))
+	}
+	for idx, img := range expected {
+		if collection.Images[idx].Path != img.Path || collection.Images[idx].Ext != img.Ext {
+			t.Errorf("index %d: expected %+v, got %+v", idx, img, collection.Images[idx])
+		}
+	}
+
+	if collection.CoverImage.Path != expected[1].Path {
+		t.Errorf("expected cover %+v, got %+v", expected[1], collection.CoverImage)
+	}
+}
+
+func TestFilesystemService_GetCollection_DefaultsTitleAndCover(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+	writeManifest(t, collectionsDir, "clients", "images:\n  - Wildlife/bear.jpg\n  - Landscape/mountains.jpg\n")
+
+	svc := NewFilesystemService(root, "", WithCollections(collectionsDir))
+
+	collection, err := svc.GetCollection("clients")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if collection.Title != "clients" {
+		t.Errorf("expected title to default to the manifest name, got %q", collection.Title)
+	}
+	if collection.CoverImage.Path != filepath.Join("Wildlife", "bear") {
+		t.Errorf("expected cover to default to the first image, got %s", collection.CoverImage.Path)
+	}
+}
+
+func TestFilesystemService_GetCollection_SkipsUnresolvedImages(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+	createAlbumMetadata(t, filepath.Join(root, "Alaska"), "private: true\n")
+	writeManifest(t, collectionsDir, "stale", `
+cover: Landscape/nope.jpg
+images:
+  - Landscape/nope.jpg
+  - ../secret.jpg
+  - Alaska/2018/glacier.png
+  - Wildlife/bear.jpg
+`)
+	writeManifest(t, collectionsDir, "gone", "images: [Landscape/nope.jpg]\n")
+
+	var warnings bytes.Buffer
+	log.SetOutput(&warnings)
+	defer log.SetOutput(os.Stderr)
+
+	svc := NewFilesystemService(root, "/assets/portfolio", WithCollections(collectionsDir))
+	collection, err := svc.GetCollection("stale")
+	if err != nil {
+		t.Fatalf("expected unresolved images to be skipped, got %v", err)
+	}
+	bear := filepath.Join("/assets/portfolio", "Wildlife", "bear")
+	if len(collection.Images) != 1 || collection.Images[0].Path != bear || collection.CoverImage.Path != bear {
+		t.Errorf("expected only the bear, which becomes the cover, got %+v", collection)
+	}
+	for _, ref := range []string{"Landscape/nope.jpg", "../secret.jpg", "Alaska/2018/glacier.png"} {
+		if !strings.Contains(warnings.String(), ref) {
+			t.Errorf("expected a warning about %s, got %q", ref, warnings.String())
+		}
+	}
+
+	collections, err := svc.GetCollections()
+	if err != nil || len(collections) != 2 || len(collections[0].Images) != 0 {
+		t.Errorf("expected every collection to be listed, emptied ones included, got %+v, %v", collections, err)
+	}
+}
+
+func TestFilesystemService_GetCollection_NotFound(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+
+	for _, svc := range []Service{
+		NewFilesystemService(root, "", WithCollections(collectionsDir)),
+		NewFilesystemService(root, ""),
+	} {
+		for _, name := range []string{"absent", "../absent", ""} {
+			if _, err := svc.GetCollection(name); err != ErrCollectionNotFound {
+				t.Errorf("GetCollection(%q): expected ErrCollectionNotFound, got %v", name, err)
+			}
+		}
+	}
+}
+
+func TestFilesystemService_GetCollections_SortedByName(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+	writeManifest(t, collectionsDir, "zeta", "images: [Landscape/mountains.jpg]\n")
+	writeManifest(t, collectionsDir, "alpha", "images: [Wildlife/bear.jpg]\n")
+	createTempFile(t, filepath.Join(collectionsDir, "README.md"))
+
+	svc := NewFilesystemService(root, "", WithCollections(collectionsDir))
+
+	collections, err := svc.GetCollections()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(collections) != 2 || collections[0].Name != "alpha" || collections[1].Name != "zeta" {
+		t.Errorf("expected collections [alpha zeta], got %+v", collections)
+	}
+}
+
+func TestFilesystemService_GetCollection_LeavesOutPrivateImages(t *testing.T) {
+	root, collectionsDir := setupCollectionsFixture(t)
+	createAlbumMetadata(t, filepath.Join(root, "Alaska"), "private: true\n")
+	writeManifest(t, collectionsDir, "leaky", "images:\n  - Alaska/2018/glacier.png\n")
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	svc := NewFilesystemService(root, "/assets/portfolio", WithCollections(collectionsDir))
+	if collection, err := svc.GetCollection("leaky"); err != nil || len(collection.Images) != 0 {
+		t.Errorf("expected an image from a private album to be left out, got %+v, %v", collection, err)
+	}
+}
@@ -0,0 +1,71 @@
+package portfolio
+
+import "personalwebsite/internal/images"
+
+// FeatureCollection is a GeoJSON document of photo locations.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a single geotagged photo in a FeatureCollection.
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   Point             `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// Point is a GeoJSON point. Coordinates are longitude then latitude.
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// FeatureProperties describe the photo at a point. Index is the photo's position
+// in the slice passed to GeoJSON, so the map can open it in a lightbox.
+type FeatureProperties struct {
+	Index     int    `json:"index"`
+	Album     string `json:"album"`
+	Caption   string `json:"caption,omitempty"`
+	Thumbnail string `json:"thumbnail"`
+	Permalink string `json:"permalink"`
+}
+
+// GeotaggedImages returns the images across the given categories and all of their
+// nested albums that have a published location.
+func GeotaggedImages(categories []Category) []Image {
+	var geotagged []Image
+	walkAlbums(categories, func(album Category) {
+		for _, img := range album.Images {
+			if img.Location != nil {
+				geotagged = append(geotagged, img)
+			}
+		}
+	})
+	return geotagged
+}
+
+// GeoJSON builds a FeatureCollection from photos, skipping any without a location.
+func GeoJSON(photos []Image) FeatureCollection {
+	collection := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for idx, img := range photos {
+		if img.Location == nil {
+			continue
+		}
+		collection.Features = append(collection.Features, Feature{
+			Type: "Feature",
+			Geometry: Point{
+				Type:        "Point",
+				Coordinates: [2]float64{img.Location.Longitude, img.Location.Latitude},
+			},
+			Properties: FeatureProperties{
+				Index:     idx,
+				Album:     img.Album,
+				Caption:   img.Caption,
+				Thumbnail: img.Responsive(images.GridLayout).Src,
+				Permalink: img.Permalink(),
+			},
+		})
+	}
+	return collection
+}
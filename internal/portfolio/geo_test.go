@@ -0,0 +1,123 @@
+package portfolio
+
+import (
+	"os"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"testing"
+)
+
+// createGeotaggedImage copies internal/testdata/geotagged.jpg, a small JPEG
+// whose EXIF places it at 61°13'12"N 149°53'24"W (61.22, -149.89), to path.
+func createGeotaggedImage(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "geotagged.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilesystemService_GetCategory_ReadsCoarseLocationByDefault(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, album := range []string{"Alaska", "Exact"} {
+		os.MkdirAll(filepath.Join(tmpDir, album), 0755)
+		createGeotaggedImage(t, filepath.Join(tmpDir, album, "anchorage.jpg"))
+	}
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Exact"), "location: exact\n")
+	service := NewFilesystemService(tmpDir, "/assets")
+
+	album, err := service.GetCategory("Alaska")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if location := album.Images[0].Location; location == nil || location.Latitude != 61.2 || location.Longitude != -149.9 {
+		t.Errorf("expected coarse coordinates without a location setting, got %v", location)
+	}
+
+	exact, err := service.GetCategory("Exact")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if location := exact.Images[0].Location; location == nil || location.Latitude == 61.2 || location.Longitude == -149.9 {
+		t.Errorf("expected exact coordinates once asked for, got %v", location)
+	}
+}
+
+func TestFilesystemService_GetCategory_AppliesInheritedLocationPrivacy(t *testing.T) {
+	tmpDir := t.TempDir()
+	albumDir := filepath.Join(tmpDir, "Alaska")
+	nestedDir := filepath.Join(albumDir, "2018")
+	privateDir := filepath.Join(albumDir, "Home")
+	os.MkdirAll(nestedDir, 0755)
+	os.MkdirAll(privateDir, 0755)
+	createAlbumMetadata(t, albumDir, "location: coarse\n")
+	createAlbumMetadata(t, privateDir, "location: hidden\n")
+	createGeotaggedImage(t, filepath.Join(nestedDir, "anchorage.jpg"))
+	createGeotaggedImage(t, filepath.Join(privateDir, "cabin.jpg"))
+
+	service := NewFilesystemService(tmpDir, "/assets")
+
+	// Nested albums inherit "coarse" whether reached from the category or directly.
+	categories, err := service.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	nested, err := service.GetCategory("Alaska/2018")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	for _, img := range []Image{categories[0].Albums[0].Images[0], nested.Images[0]} {
+		if img.Location == nil || img.Location.Latitude != 61.2 || img.Location.Longitude != -149.9 {
+			t.Errorf("expected coarse location 61.2,-149.9, got %v", img.Location)
+		}
+	}
+
+	private, err := service.GetCategory("Alaska/Home")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if private.Images[0].Location != nil {
+		t.Errorf("expected hidden location, got %v", *private.Images[0].Location)
+	}
+}
+
+func TestGeoJSON_IndexesGeotaggedImages(t *testing.T) {
+	photos := []Image{
+		{Path: "/assets/Alaska/a", Ext: ".jpg", Album: "Alaska", Location: &images.GeoPoint{Latitude: 61.2, Longitude: -149.9}},
+		{Path: "/assets/Alaska/b", Ext: ".jpg", Album: "Alaska"},
+		{Path: "/assets/Alaska/c", Ext: ".jpg", Album: "Alaska", Caption: "Denali", Location: &images.GeoPoint{Latitude: 63.1, Longitude: -151}, Widths: []int{600}},
+	}
+
+	collection := GeoJSON(photos)
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("expected a FeatureCollection with 2 features, got %+v", collection)
+	}
+	feature := collection.Features[1]
+	if feature.Geometry.Coordinates != [2]float64{-151, 63.1} {
+		t.Errorf("expected [lon, lat] coordinates, got %v", feature.Geometry.Coordinates)
+	}
+	if feature.Properties.Index != 2 || feature.Properties.Caption != "Denali" {
+		t.Errorf("unexpected properties %+v", feature.Properties)
+	}
+	if feature.Properties.Thumbnail != "/assets/Alaska/c_w600.jpg" || feature.Properties.Permalink != "/portfolio/Alaska/c" {
+		t.Errorf("unexpected links %+v", feature.Properties)
+	}
+}
+
+func TestGeotaggedImages_IncludesNestedAlbums(t *testing.T) {
+	point := &images.GeoPoint{Latitude: 61.2, Longitude: -149.9}
+	categories := []Category{
+		{Name: "Alaska", Images: []Image{{Path: "a"}}, Albums: []Category{
+			{Name: "2018", Images: []Image{{Path: "b", Location: point}}},
+		}},
+		{Name: "Landscape", Images: []Image{{Path: "c", Location: point}}},
+	}
+
+	geotagged := GeotaggedImages(categories)
+	if len(geotagged) != 2 || geotagged[0].Path != "b" || geotagged[1].Path != "c" {
+		t.Errorf("expected [b c], got %v", geotagged)
+	}
+}
@@ -0,0 +1,33 @@
+package portfolio
+
+import "sort"
+
+// IndexKeywords maps each keyword to the images tagged with it across the given
+// categories and all of their nested albums.
+func IndexKeywords(categories []Category) map[string][]Image {
+	index := make(map[string][]Image)
+	walkAlbums(categories, func(album Category) {
+		for _, img := range album.Images {
+			for _, keyword := range img.Keywords {
+				index[keyword] = append(index[keyword], img)
+			}
+		}
+	})
+	return index
+}
+
+// Keywords returns the distinct keywords used by images, sorted alphabetically.
+func Keywords(images []Image) []string {
+	seen := make(map[string]bool)
+	var keywords []string
+	for _, img := range images {
+		for _, keyword := range img.Keywords {
+			if !seen[keyword] {
+				seen[keyword] = true
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+	sort.Strings(keywords)
+	return keywords
+}
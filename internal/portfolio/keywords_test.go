@@ -0,0 +1,71 @@
+package portfolio
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilesystemService_GetCategory_MergesSidecarAndAlbumTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	wildlifeDir := filepath.Join(tmpDir, "Wildlife")
+	if err := os.Mkdir(wildlifeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createTempFile(t, filepath.Join(wildlifeDir, "bear.jpg"))
+	createTempFile(t, filepath.Join(wildlifeDir, "eagle.jpg"))
+	sidecar := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:subject><rdf:Bag><rdf:li>Bear</rdf:li><rdf:li>River</rdf:li></rdf:Bag></dc:subject></rdf:Description>
+</rdf:RDF></x:xmpmeta>`
+	if err := os.WriteFile(filepath.Join(wildlifeDir, "bear.xmp"), []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createAlbumMetadata(t, wildlifeDir, "photos:\n  bear.jpg:\n    tags: [alaska, bear]\n  eagle.jpg:\n    tags: [bird]\n")
+
+	cat, err := NewFilesystemService(tmpDir, "").GetCategory("Wildlife")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(cat.Images[0].Keywords, []string{"bear", "river", "alaska"}) {
+		t.Errorf("expected bear keywords [bear river alaska], got %v", cat.Images[0].Keywords)
+	}
+	if !reflect.DeepEqual(cat.Images[1].Keywords, []string{"bird"}) {
+		t.Errorf("expected eagle keywords [bird], got %v", cat.Images[1].Keywords)
+	}
+}
+
+func TestIndexKeywords_IncludesNestedAlbums(t *testing.T) {
+	bear := Image{Path: "Wildlife/bear", Keywords: []string{"bear", "alaska"}}
+	glacier := Image{Path: "Alaska/2018/glacier", Keywords: []string{"alaska", "glacier"}}
+	categories := []Category{
+		{Name: "Wildlife", Images: []Image{bear}},
+		{Name: "Alaska", Albums: []Category{{Name: "2018", Images: []Image{glacier}}}},
+	}
+
+	index := IndexKeywords(categories)
+
+	if len(index["alaska"]) != 2 {
+		t.Errorf("expected 2 images tagged alaska, got %d", len(index["alaska"]))
+	}
+	if len(index["glacier"]) != 1 || index["glacier"][0].Path != glacier.Path {
+		t.Errorf("expected glacier tagged glacier, got %+v", index["glacier"])
+	}
+	if _, ok := index["portrait"]; ok {
+		t.Errorf("expected no entry for an unused keyword")
+	}
+}
+
+func TestKeywords_SortedAndDistinct(t *testing.T) {
+	got := Keywords([]Image{
+		{Keywords: []string{"river", "bear"}},
+		{Keywords: []string{"bear", "alaska"}},
+		{},
+	})
+
+	if !reflect.DeepEqual(got, []string{"alaska", "bear", "river"}) {
+		t.Errorf("expected [alaska bear river], got %v", got)
+	}
+}
@@ -0,0 +1,308 @@
+package portfolio
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"personalwebsite/internal/images"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AlbumMetadataFile is the optional per-album settings file read from each album directory.
+const AlbumMetadataFile = "album.yaml"
+
+// BrandingDir holds watermark images at the top of the portfolio root. Being a
+// dot-directory it is never scanned as an album; cmd/optimize copies it as-is so
+// the published tree resolves the same marks.
+const BrandingDir = ".branding"
+
+// Sort modes accepted in the album metadata "sort" field.
+const (
+	SortFilename = "filename"
+	SortDateAsc  = "date"
+	SortDateDesc = "date-desc"
+	SortManual   = "manual"
+	SortRandom   = "random"
+)
+
+type albumMetadata struct {
+	Private      bool                     `yaml:"private"`
+	PasswordHash string                   `yaml:"password_hash"`
+	Downloadable *bool                    `yaml:"downloadable"`
+	Watermark    *watermarkMetadata       `yaml:"watermark"`
+	Location     string                   `yaml:"location"`
+	Metadata     []string                 `yaml:"metadata"`
+	Sort         string                   `yaml:"sort"`
+	Order        []string                 `yaml:"order"`
+	Seed         int64                    `yaml:"seed"`
+	Cover        string                   `yaml:"cover"`
+	Photos       map[string]photoMetadata `yaml:"photos"`
+}
+
+// watermarkMetadata configures the mark drawn over large variants. Image is a
+// PNG path relative to the album directory that must lie in BrandingDir; an
+// empty watermark turns off the one inherited from a parent album.
+type watermarkMetadata struct {
+	Text     string  `yaml:"text"`
+	Image    string  `yaml:"image"`
+	Position string  `yaml:"position"`
+	Opacity  float64 `yaml:"opacity"`
+}
+
+// photoMetadata holds per-photo settings keyed by file name under "photos".
+type photoMetadata struct {
+	Tags    []string  `yaml:"tags"`
+	Caption string    `yaml:"caption"`
+	Prints  []string  `yaml:"prints"` // print sizes offered, e.g. ["8x10", "12x18"]
+	Focus   []float64 `yaml:"focus"`  // [x, y] fractions from the top-left corner that crops centre on
+}
+
+// focalPoint returns the photo's focus setting, or nil when it has no valid one.
+func (meta photoMetadata) focalPoint() *images.FocalPoint {
+	if len(meta.Focus) != 2 {
+		return nil
+	}
+	focus := images.FocalPoint{X: meta.Focus[0], Y: meta.Focus[1]}
+	if !focus.Valid() {
+		return nil
+	}
+	return &focus
+}
+
+// Location modes accepted in the album metadata "location" field. Nested albums
+// inherit their parent's mode unless they set their own.
+const (
+	LocationExact  = "exact"
+	LocationCoarse = "coarse"
+	LocationHidden = "hidden"
+)
+
+// albumSettings are the album settings that nested albums inherit.
+type albumSettings struct {
+	Access       Access
+	Downloadable bool
+	Location     string
+	Metadata     []string
+	// Watermark.Image is slash-separated and relative to the portfolio root.
+	Watermark images.Watermark
+}
+
+// apply layers the metadata of the album at albumPath over the settings
+// inherited from its parents.
+func (settings albumSettings) apply(albumPath string, meta albumMetadata) albumSettings {
+	if meta.Private {
+		settings.Access = Access{Album: albumPath, PasswordHash: meta.PasswordHash}
+	}
+	if meta.Downloadable != nil {
+		settings.Downloadable = *meta.Downloadable
+	}
+	if meta.Location != "" {
+		settings.Location = meta.Location
+	}
+	if meta.Metadata != nil {
+		settings.Metadata = meta.Metadata
+	}
+	if meta.Watermark != nil {
+		settings.Watermark = images.Watermark{
+			Text:     meta.Watermark.Text,
+			Position: meta.Watermark.Position,
+			Opacity:  meta.Watermark.Opacity,
+		}
+		if meta.Watermark.Image != "" {
+			settings.Watermark.Image = path.Join(albumPath, meta.Watermark.Image)
+		}
+	}
+	return settings
+}
+
+// metadataPolicy is the policy for images published from the album. The
+// embedded location follows the album's location mode, coarse unless exact
+// coordinates were asked for.
+func (settings albumSettings) metadataPolicy() images.MetadataPolicy {
+	policy := images.MetadataPolicy{GPS: images.GPSCoarse, Keep: settings.Metadata}
+	switch settings.Location {
+	case LocationHidden:
+		policy.GPS = images.GPSStrip
+	case LocationExact:
+		policy.GPS = images.GPSExact
+	}
+	return policy
+}
+
+// LoadAccess returns who may view the album at the slash-separated albumPath
+// under root, honouring private parents.
+func LoadAccess(root, albumPath string) (Access, error) {
+	album, err := loadAlbumAt(root, albumPath)
+	if err != nil {
+		return Access{}, err
+	}
+	return album.settings.Access, nil
+}
+
+// LoadMetadataPolicy returns the metadata policy for images in the album at the
+// slash-separated albumPath under root, honouring settings inherited from its parents.
+func LoadMetadataPolicy(root, albumPath string) (images.MetadataPolicy, error) {
+	album, err := loadAlbumAt(root, albumPath)
+	if err != nil {
+		return images.MetadataPolicy{}, err
+	}
+	return album.settings.metadataPolicy(), nil
+}
+
+// LoadWatermark returns the watermark for variants of images in the album at the
+// slash-separated albumPath under root, with Image resolved to a file path.
+func LoadWatermark(root, albumPath string) (images.Watermark, error) {
+	album, err := loadAlbumAt(root, albumPath)
+	if err != nil {
+		return images.Watermark{}, err
+	}
+	watermark := album.settings.Watermark
+	if watermark.Image != "" {
+		if !strings.HasPrefix(watermark.Image, BrandingDir+"/") {
+			return images.Watermark{}, fmt.Errorf("watermark image %q is outside %s", watermark.Image, BrandingDir)
+		}
+		watermark.Image = filepath.Join(root, filepath.FromSlash(watermark.Image))
+	}
+	return watermark, nil
+}
+
+// Watermarks looks up watermarks with LoadWatermark for images under root, by
+// path relative to root.
+func Watermarks(root string) images.WatermarkFunc {
+	return func(relPath string) (images.Watermark, error) {
+		return LoadWatermark(root, filepath.ToSlash(filepath.Dir(relPath)))
+	}
+}
+
+// FocalPoints looks up the focus set in album metadata for images under root,
+// by path relative to root. Images without one get nil.
+func FocalPoints(root string) images.FocusFunc {
+	return func(relPath string) (*images.FocalPoint, error) {
+		album, err := loadAlbumAt(root, filepath.ToSlash(filepath.Dir(relPath)))
+		if err != nil {
+			return nil, err
+		}
+		return album.meta.Photos[filepath.Base(relPath)].focalPoint(), nil
+	}
+}
+
+// loadAlbumAt reads the album at albumPath under root; "" or "." is the root itself.
+func loadAlbumAt(root, albumPath string) (albumContext, error) {
+	var segments []string
+	if albumPath != "" && albumPath != "." {
+		var ok bool
+		if segments, ok = splitAlbumPath(albumPath); !ok {
+			return albumContext{}, ErrCategoryNotFound
+		}
+	}
+	s := &filesystemService{root: root}
+	return s.loadAlbumContext(segments)
+}
+
+// publishedLocation applies the album's location privacy to a photo's GPS
+// position, which is coarse unless exact coordinates were asked for.
+func (settings albumSettings) publishedLocation(location *images.GeoPoint) *images.GeoPoint {
+	if location == nil {
+		return nil
+	}
+	switch settings.Location {
+	case LocationHidden:
+		return nil
+	case LocationExact:
+		return location
+	}
+	coarse := location.Coarsen()
+	return &coarse
+}
+
+func loadAlbumMetadata(dirPath string) (albumMetadata, error) {
+	var meta albumMetadata
+	content, err := os.ReadFile(filepath.Join(dirPath, AlbumMetadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return meta, err
+	}
+	if err := yaml.Unmarshal(content, &meta); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// sortImages orders images according to the album's sort mode. Filename order is
+// the default and the tie-breaker for every other mode.
+func sortImages(images []Image, meta albumMetadata) {
+	sort.Slice(images, func(idx, jdx int) bool {
+		return images[idx].Path < images[jdx].Path
+	})
+
+	switch meta.Sort {
+	case SortDateAsc, SortDateDesc:
+		descending := meta.Sort == SortDateDesc
+		sort.SliceStable(images, func(idx, jdx int) bool {
+			left, right := images[idx].CaptureTime, images[jdx].CaptureTime
+			// Undated images always go last.
+			if left.IsZero() || right.IsZero() {
+				return !left.IsZero() && right.IsZero()
+			}
+			if descending {
+				return left.After(right)
+			}
+			return left.Before(right)
+		})
+	case SortManual:
+		position := make(map[string]int, len(meta.Order))
+		for idx, name := range meta.Order {
+			position[name] = idx
+		}
+		sort.SliceStable(images, func(idx, jdx int) bool {
+			left, leftListed := position[images[idx].FileName()]
+			right, rightListed := position[images[jdx].FileName()]
+			if leftListed && rightListed {
+				return left < right
+			}
+			return leftListed && !rightListed
+		})
+	case SortRandom:
+		rng := rand.New(rand.NewSource(meta.Seed))
+		rng.Shuffle(len(images), func(idx, jdx int) {
+			images[idx], images[jdx] = images[jdx], images[idx]
+		})
+	}
+}
+
+// selectCover honours an explicit cover override, otherwise picks the last image
+// alphabetically, falling back to the cover of the last nested album that has
+// one when the album holds no images directly.
+func selectCover(images []Image, albums []Category, meta albumMetadata) Image {
+	if meta.Cover != "" {
+		for _, img := range images {
+			if img.FileName() == meta.Cover {
+				return img
+			}
+		}
+	}
+
+	var cover Image
+	for _, img := range images {
+		if img.Path > cover.Path {
+			cover = img
+		}
+	}
+	if cover.Path != "" {
+		return cover
+	}
+
+	for idx := len(albums) - 1; idx >= 0; idx-- {
+		if albums[idx].CoverImage.Path != "" {
+			return albums[idx].CoverImage
+		}
+	}
+	return Image{}
+}
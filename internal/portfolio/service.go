@@ -3,50 +3,175 @@ package portfolio
 import (
 	"errors"
 	"os"
+	"path"
 	"path/filepath"
+	"personalwebsite/internal/images"
 	"sort"
 	"strings"
+	"time"
 )
 
 var ErrCategoryNotFound = errors.New("category not found")
 
+// Image is a published photo. Album is the slash-separated path of the album
+// the photo lives in, which may differ from where it is shown (collections, tags).
+// PrintSizes lists the print sizes visitors may request; none means no prints.
+// Widths lists the images.FullLadder widths its variants are published at, and
+// Formats names the images.ModernFormats those variants also exist in.
+// CoverWidths lists the images.CoverLadder widths its crops are published at.
+// Fingerprints holds the images.Fingerprint of those variants and crops, by
+// file name, which fingerprints their URLs.
 type Image struct {
+	Path         string
+	Ext          string
+	Album        string
+	Caption      string
+	CaptureTime  time.Time
+	Exposure     images.Exposure
+	Location     *images.GeoPoint
+	Keywords     []string
+	PrintSizes   []string
+	Widths       []int
+	Formats      []string
+	CoverWidths  []int
+	Fingerprints map[string]string
+}
+
+// FileName returns the image's file name within its album, e.g. "DSC01260.jpg".
+func (img Image) FileName() string {
+	return filepath.Base(img.Path) + img.Ext
+}
+
+// Slug identifies the image within its album in permalinks, e.g. "DSC01260".
+func (img Image) Slug() string {
+	return filepath.Base(img.Path)
+}
+
+// Permalink returns the URL of the image's standalone page.
+func (img Image) Permalink() string {
+	return "/portfolio/" + img.Album + "/" + img.Slug()
+}
+
+// PrintOrderURL returns the URL of the form requesting prints of the image.
+func (img Image) PrintOrderURL() string {
+	return "/prints/" + img.Album + "/" + img.Slug()
+}
+
+// Responsive describes the image's published images.FullLadder variants laid
+// out as layout.
+func (img Image) Responsive(layout images.Layout) images.Responsive {
+	return images.FullLadder.Only(img.Widths).Fingerprinted(img.fingerprint).Responsive(img.Path, img.Ext, layout, img.Formats)
+}
+
+// ResponsiveCover describes the image's images.CoverLadder crops laid out as
+// layout, or its uncropped variants when no crop has been published.
+func (img Image) ResponsiveCover(layout images.Layout) images.Responsive {
+	if len(img.CoverWidths) == 0 {
+		return img.Responsive(layout)
+	}
+	return images.CoverLadder.Only(img.CoverWidths).Fingerprinted(img.fingerprint).Responsive(img.Path, img.Ext, layout, nil)
+}
+
+// fingerprint returns the fingerprint of the published variant at url.
+func (img Image) fingerprint(url string) string {
+	return img.Fingerprints[path.Base(url)]
+}
+
+// FindPhoto returns the image with the given slug and its neighbours within images.
+func FindPhoto(images []Image, slug string) (*Image, *Image, *Image) {
+	for idx := range images {
+		if images[idx].Slug() != slug {
+			continue
+		}
+		var prevImage, nextImage *Image
+		if idx > 0 {
+			prevImage = &images[idx-1]
+		}
+		if idx < len(images)-1 {
+			nextImage = &images[idx+1]
+		}
+		return &images[idx], prevImage, nextImage
+	}
+	return nil, nil, nil
+}
+
+// Breadcrumb links to one of an album's ancestors.
+type Breadcrumb struct {
+	Name string
 	Path string
-	Ext  string
 }
 
+// Access describes who may view an album. Album is the path of the private album
+// whose password (or share link) unlocks it, or empty when the album is public.
+type Access struct {
+	Album        string
+	PasswordHash string
+}
+
+// IsPrivate reports whether viewing the album requires unlocking Access.Album.
+func (a Access) IsPrivate() bool {
+	return a.Album != ""
+}
+
+// Category is a top-level portfolio category or one of its nested albums.
+// Path is slash-separated and relative to the portfolio root, e.g. "Alaska/2018".
+// Downloadable albums offer their photos as a ZIP archive.
 type Category struct {
-	Name       string
-	Group      string
-	Images     []Image
-	CoverImage Image
+	Name         string
+	Path         string
+	Group        string
+	Images       []Image
+	Albums       []Category
+	Parents      []Breadcrumb
+	CoverImage   Image
+	Access       Access
+	Downloadable bool
+}
+
+// walkAlbums calls fn for each of categories and, depth first, every album
+// nested in them: each album before its nested albums.
+func walkAlbums(categories []Category, fn func(Category)) {
+	for _, category := range categories {
+		fn(category)
+		walkAlbums(category.Albums, fn)
+	}
 }
 
 type Service interface {
 	GetCategories() ([]Category, error)
 	GetCategory(name string) (Category, error)
+	GetCollections() ([]Collection, error)
+	GetCollection(name string) (Collection, error)
 }
 
 type filesystemService struct {
-	root          string
-	webPathPrefix string
+	root            string
+	webPathPrefix   string
+	collectionsRoot string
 }
 
-func NewFilesystemService(root, webPathPrefix string) Service {
-	return &filesystemService{
+func NewFilesystemService(root, webPathPrefix string, opts ...Option) Service {
+	s := &filesystemService{
 		root:          root,
 		webPathPrefix: webPathPrefix,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
+// GetCategory returns the category or nested album at the given slash-separated
+// path, e.g. "Landscape" or "Alaska/2018".
 func (s *filesystemService) GetCategory(name string) (Category, error) {
-	// Security check: simple check to prevent directory traversal
-	if strings.Contains(name, "..") || strings.Contains(name, "/") || strings.Contains(name, "\\") {
+	// Security check: every segment must be a plain directory name to prevent directory traversal
+	segments, ok := splitAlbumPath(name)
+	if !ok {
 		return Category{}, ErrCategoryNotFound
 	}
 
 	// Check if directory exists
-	dirPath := filepath.Join(s.root, name)
+	dirPath := filepath.Join(s.root, filepath.Join(segments...))
 	info, err := os.Stat(dirPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -59,7 +184,36 @@ func (s *filesystemService) GetCategory(name string) (Category, error) {
 		return Category{}, ErrCategoryNotFound
 	}
 
-	return s.scanCategory(name)
+	parent, err := s.loadAlbumContext(segments[:len(segments)-1])
+	if err != nil {
+		return Category{}, err
+	}
+
+	return s.scanCategory(strings.Join(segments, "/"), breadcrumbsFor(segments), parent.settings)
+}
+
+func splitAlbumPath(albumPath string) ([]string, bool) {
+	if albumPath == "" || strings.Contains(albumPath, "\\") {
+		return nil, false
+	}
+	segments := strings.Split(albumPath, "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.HasPrefix(segment, ".") {
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+func breadcrumbsFor(segments []string) []Breadcrumb {
+	var parents []Breadcrumb
+	for idx := 0; idx < len(segments)-1; idx++ {
+		parents = append(parents, Breadcrumb{
+			Name: segments[idx],
+			Path: strings.Join(segments[:idx+1], "/"),
+		})
+	}
+	return parents
 }
 
 var preferredOrder = []string{"Landscape", "People", "Wildlife", "Structures"}
@@ -77,6 +231,9 @@ func GroupCategories(categories []Category) ([]Category, []Category) {
 	var portfolioCategories []Category
 	var adventureCats []Category
 	for _, cat := range categories {
+		if cat.Access.IsPrivate() {
+			continue
+		}
 		if cat.Group == "adventure" {
 			adventureCats = append(adventureCats, cat)
 		} else {
@@ -94,7 +251,7 @@ func (s *filesystemService) GetCategories() ([]Category, error) {
 
 	existingDirs := make(map[string]bool)
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			existingDirs[entry.Name()] = true
 		}
 	}
@@ -103,12 +260,13 @@ func (s *filesystemService) GetCategories() ([]Category, error) {
 
 	for _, catName := range preferredOrder {
 		if existingDirs[catName] {
-			cat, err := s.scanCategory(catName)
+			cat, err := s.scanCategory(catName, nil, albumSettings{})
 			if err != nil {
 				return nil, err
 			}
-			cat.Group = groupForCategory(catName)
-			categories = append(categories, cat)
+			if !cat.Access.IsPrivate() {
+				categories = append(categories, cat)
+			}
 			delete(existingDirs, catName)
 		}
 	}
@@ -120,58 +278,216 @@ func (s *filesystemService) GetCategories() ([]Category, error) {
 	sort.Strings(remaining)
 
 	for _, catName := range remaining {
-		cat, err := s.scanCategory(catName)
+		cat, err := s.scanCategory(catName, nil, albumSettings{})
 		if err != nil {
 			return nil, err
 		}
-		cat.Group = groupForCategory(catName)
-		categories = append(categories, cat)
+		if !cat.Access.IsPrivate() {
+			categories = append(categories, cat)
+		}
 	}
 
 	return categories, nil
 }
 
-func (s *filesystemService) scanCategory(categoryName string) (Category, error) {
-	dirPath := filepath.Join(s.root, categoryName)
+// scanCategory reads the album at albumPath along with all of its nested albums.
+// inherited holds the settings in effect from the album's ancestors.
+func (s *filesystemService) scanCategory(albumPath string, parents []Breadcrumb, inherited albumSettings) (Category, error) {
+	dirPath := filepath.Join(s.root, filepath.FromSlash(albumPath))
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		return Category{}, err
 	}
 
-	var images []Image
+	meta, err := loadAlbumMetadata(dirPath)
+	if err != nil {
+		return Category{}, err
+	}
+	album := albumContext{path: albumPath, dirPath: dirPath, meta: meta, settings: inherited.apply(albumPath, meta), files: fileNames(entries)}
+
+	segments := strings.Split(albumPath, "/")
+	albumName := segments[len(segments)-1]
+	childParents := append(append([]Breadcrumb{}, parents...), Breadcrumb{Name: albumName, Path: albumPath})
+
+	var albumImages []Image
+	var albums []Category
 
 	for _, entry := range entries {
+		name := entry.Name()
+
 		if entry.IsDir() {
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			child, err := s.scanCategory(albumPath+"/"+name, childParents, album.settings)
+			if err != nil {
+				return Category{}, err
+			}
+			// Albums locked separately from this one are left out of its listing.
+			if child.Access == album.settings.Access {
+				albums = append(albums, child)
+			}
 			continue
 		}
 
-		name := entry.Name()
 		ext := strings.ToLower(filepath.Ext(name))
 
-		if strings.Contains(name, "_w600") || strings.Contains(name, "_w1600") {
+		if images.IsVariantName(name) {
 			continue
 		}
 
 		if ext == ".jpg" || ext == ".jpeg" || ext == ".png" {
-			baseName := strings.TrimSuffix(name, ext)
-			imgPath := filepath.Join(s.webPathPrefix, categoryName, baseName)
+			img, err := s.readImage(album, name, ext)
+			if err != nil {
+				return Category{}, err
+			}
+			albumImages = append(albumImages, img)
+		}
+	}
+
+	sortImages(albumImages, meta)
 
-			images = append(images, Image{Path: imgPath, Ext: ext})
+	return Category{
+		Name:         albumName,
+		Path:         albumPath,
+		Group:        groupForCategory(segments[0]),
+		Images:       albumImages,
+		Albums:       albums,
+		Parents:      parents,
+		CoverImage:   selectCover(albumImages, albums, meta),
+		Access:       album.settings.Access,
+		Downloadable: album.settings.Downloadable,
+	}, nil
+}
+
+// albumContext is what readImage needs to know about the album holding a photo.
+type albumContext struct {
+	path     string
+	dirPath  string
+	meta     albumMetadata
+	settings albumSettings
+	// files names the album directory's files, which readImage checks for
+	// published variants.
+	files map[string]bool
+}
+
+// publishedWidths lists the widths of ladder at which the image named
+// baseName+ext has variants in the album.
+func (album albumContext) publishedWidths(ladder images.Ladder, baseName, ext string) []int {
+	var widths []int
+	for _, width := range ladder.Widths {
+		if album.files[ladder.Variant(baseName, ext, width)] {
+			widths = append(widths, width)
 		}
 	}
+	return widths
+}
 
-	sort.Slice(images, func(idx, jdx int) bool {
-		return images[idx].Path < images[jdx].Path
-	})
+// modernFormats lists the formats, as images.Format names, in which the image
+// named baseName was published at every one of widths.
+func (album albumContext) modernFormats(baseName string, widths []int) []string {
+	var formats []string
+	for _, format := range images.ModernFormats {
+		published := len(widths) > 0
+		for _, width := range widths {
+			published = published && album.files[images.FullLadder.Variant(baseName, "."+format.Name, width)]
+		}
+		if published {
+			formats = append(formats, format.Name)
+		}
+	}
+	return formats
+}
 
-	var coverImage Image
-	if len(images) > 0 {
-		coverImage = images[len(images)-1]
+// fingerprints returns the images.Fingerprint of each variant of the image
+// named baseName+ext published at widths, in its own and the modern formats,
+// and of its crops published at coverWidths, by file name.
+func (album albumContext) fingerprints(baseName, ext string, widths []int, formats []string, coverWidths []int) map[string]string {
+	var names []string
+	for _, width := range widths {
+		names = append(names, images.FullLadder.Variant(baseName, ext, width))
+		for _, format := range formats {
+			names = append(names, images.FullLadder.Variant(baseName, "."+format, width))
+		}
+	}
+	for _, width := range coverWidths {
+		names = append(names, images.CoverLadder.Variant(baseName, ext, width))
 	}
+	fingerprints := map[string]string{}
+	for _, name := range names {
+		// A variant that cannot be read is linked without a fingerprint.
+		if hash, err := images.Fingerprint(filepath.Join(album.dirPath, name)); err == nil {
+			fingerprints[name] = hash
+		}
+	}
+	return fingerprints
+}
 
-	return Category{
-		Name:       categoryName,
-		Images:     images,
-		CoverImage: coverImage,
+// loadAlbumContext reads the album at the given path segments, applying the
+// settings of every ancestor on the way down.
+func (s *filesystemService) loadAlbumContext(segments []string) (albumContext, error) {
+	var album albumContext
+	for idx := range segments {
+		dirPath := filepath.Join(s.root, filepath.Join(segments[:idx+1]...))
+		meta, err := loadAlbumMetadata(dirPath)
+		if err != nil {
+			return albumContext{}, err
+		}
+		albumPath := strings.Join(segments[:idx+1], "/")
+		album = albumContext{
+			path:     albumPath,
+			dirPath:  dirPath,
+			meta:     meta,
+			settings: album.settings.apply(albumPath, meta),
+		}
+	}
+	return album, nil
+}
+
+// fileNames returns the names of the files, but not directories, among entries.
+func fileNames(entries []os.DirEntry) map[string]bool {
+	files := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files[entry.Name()] = true
+		}
+	}
+	return files
+}
+
+// readImage combines embedded metadata with keywords from an .xmp sidecar and
+// the album's "photos" settings.
+func (s *filesystemService) readImage(album albumContext, fileName, ext string) (Image, error) {
+	embedded, err := images.ReadMetadata(filepath.Join(album.dirPath, fileName))
+	if err != nil {
+		return Image{}, err
+	}
+
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	sidecarKeywords, err := images.ReadSidecarKeywords(filepath.Join(album.dirPath, baseName+".xmp"))
+	if err != nil {
+		return Image{}, err
+	}
+
+	keywords := append(embedded.Keywords, sidecarKeywords...)
+	keywords = append(keywords, album.meta.Photos[fileName].Tags...)
+	widths := album.publishedWidths(images.FullLadder, baseName, ext)
+	formats := album.modernFormats(baseName, widths)
+	coverWidths := album.publishedWidths(images.CoverLadder, baseName, ext)
+
+	return Image{
+		Path:         filepath.Join(s.webPathPrefix, filepath.FromSlash(album.path), strings.TrimSuffix(fileName, ext)),
+		Ext:          ext,
+		Album:        album.path,
+		Caption:      album.meta.Photos[fileName].Caption,
+		CaptureTime:  embedded.CaptureTime,
+		Exposure:     embedded.Exposure,
+		Location:     album.settings.publishedLocation(embedded.Location),
+		Keywords:     images.NormalizeKeywords(keywords),
+		PrintSizes:   album.meta.Photos[fileName].Prints,
+		Widths:       widths,
+		Formats:      formats,
+		CoverWidths:  coverWidths,
+		Fingerprints: album.fingerprints(baseName, ext, widths, formats, coverWidths),
 	}, nil
 }
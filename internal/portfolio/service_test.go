@@ -1,9 +1,16 @@
 package portfolio
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
+	"personalwebsite/internal/images"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func createTempFile(t *testing.T, path string) {
@@ -213,6 +220,196 @@ func TestFilesystemService_GetCategories_LandscapeCoverImageIsLastAlphabetically
 	}
 }
 
+func createAlbumMetadata(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, AlbumMetadataFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createDatedImage writes a stub JPEG carrying only an EXIF DateTimeOriginal.
+func createDatedImage(t *testing.T, path string, captured time.Time) {
+	t.Helper()
+	var tiff bytes.Buffer
+	le := binary.LittleEndian
+	tiff.WriteString("II")
+	binary.Write(&tiff, le, uint16(42))
+	binary.Write(&tiff, le, uint32(8))
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, []uint16{0x8769, 4})
+	binary.Write(&tiff, le, []uint32{1, 26, 0})
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, []uint16{0x9003, 2})
+	binary.Write(&tiff, le, []uint32{20, 44, 0})
+	tiff.WriteString(captured.Format("2006:01:02 15:04:05") + "\x00")
+
+	var out bytes.Buffer
+	segment := images.Segment{Marker: 0xE1, Data: append([]byte("Exif\x00\x00"), tiff.Bytes()...)}
+	if err := images.InsertSegments(&out, []byte{0xFF, 0xD8, 0xFF, 0xD9}, []images.Segment{segment}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func imageNames(cat Category) []string {
+	var names []string
+	for _, img := range cat.Images {
+		names = append(names, img.FileName())
+	}
+	return names
+}
+
+func assertImageOrder(t *testing.T, cat Category, expected []string) {
+	t.Helper()
+	got := imageNames(cat)
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected order %v, got %v", expected, got)
+	}
+}
+
+func TestFilesystemService_GetCategory_CoverOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+	landscapeDir := filepath.Join(tmpDir, "Landscape")
+	if err := os.Mkdir(landscapeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createTempFile(t, filepath.Join(landscapeDir, "a_first.jpg"))
+	createTempFile(t, filepath.Join(landscapeDir, "lake.png"))
+	createTempFile(t, filepath.Join(landscapeDir, "z_last.jpg"))
+	createAlbumMetadata(t, landscapeDir, "cover: lake.png\n")
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	cat, err := svc.GetCategory("Landscape")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedCoverPath := filepath.Join("Landscape", "lake")
+	if cat.CoverImage.Path != expectedCoverPath || cat.CoverImage.Ext != ".png" {
+		t.Errorf("expected cover image %s.png, got %s%s", expectedCoverPath, cat.CoverImage.Path, cat.CoverImage.Ext)
+	}
+}
+
+func TestFilesystemService_GetCategory_UnknownCoverFallsBackToLastAlphabetically(t *testing.T) {
+	tmpDir := t.TempDir()
+	landscapeDir := filepath.Join(tmpDir, "Landscape")
+	if err := os.Mkdir(landscapeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createTempFile(t, filepath.Join(landscapeDir, "a_first.jpg"))
+	createTempFile(t, filepath.Join(landscapeDir, "z_last.jpg"))
+	createAlbumMetadata(t, landscapeDir, "sort: manual\norder: [z_last.jpg, a_first.jpg]\ncover: missing.jpg\n")
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	cat, err := svc.GetCategory("Landscape")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedCoverPath := filepath.Join("Landscape", "z_last")
+	if cat.CoverImage.Path != expectedCoverPath {
+		t.Errorf("expected cover image path %s, got %s", expectedCoverPath, cat.CoverImage.Path)
+	}
+}
+
+func TestFilesystemService_GetCategory_ManualOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	landscapeDir := filepath.Join(tmpDir, "Landscape")
+	if err := os.Mkdir(landscapeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a_first.jpg", "lake.png", "mountains.jpg", "z_last.jpg"} {
+		createTempFile(t, filepath.Join(landscapeDir, name))
+	}
+	createAlbumMetadata(t, landscapeDir, "sort: manual\norder:\n  - mountains.jpg\n  - a_first.jpg\n")
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	cat, err := svc.GetCategory("Landscape")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Listed images come first, the rest follow in filename order.
+	assertImageOrder(t, cat, []string{"mountains.jpg", "a_first.jpg", "lake.png", "z_last.jpg"})
+}
+
+func TestFilesystemService_GetCategory_SortByCaptureTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	wildlifeDir := filepath.Join(tmpDir, "Wildlife")
+	if err := os.Mkdir(wildlifeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createDatedImage(t, filepath.Join(wildlifeDir, "a_moose.jpg"), time.Date(2024, 8, 1, 9, 0, 0, 0, time.UTC))
+	createDatedImage(t, filepath.Join(wildlifeDir, "b_bear.jpg"), time.Date(2023, 7, 4, 18, 0, 0, 0, time.UTC))
+	createDatedImage(t, filepath.Join(wildlifeDir, "c_eagle.jpg"), time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC))
+	createTempFile(t, filepath.Join(wildlifeDir, "undated.jpg"))
+
+	tests := []struct {
+		sortMode string
+		expected []string
+	}{
+		{SortDateAsc, []string{"b_bear.jpg", "c_eagle.jpg", "a_moose.jpg", "undated.jpg"}},
+		{SortDateDesc, []string{"a_moose.jpg", "c_eagle.jpg", "b_bear.jpg", "undated.jpg"}},
+		{SortFilename, []string{"a_moose.jpg", "b_bear.jpg", "c_eagle.jpg", "undated.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortMode, func(t *testing.T) {
+			createAlbumMetadata(t, wildlifeDir, "sort: "+tt.sortMode+"\n")
+
+			cat, err := NewFilesystemService(tmpDir, "").GetCategory("Wildlife")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			assertImageOrder(t, cat, tt.expected)
+		})
+	}
+}
+
+func TestFilesystemService_GetCategory_RandomOrderIsStableForSeed(t *testing.T) {
+	tmpDir := t.TempDir()
+	peopleDir := filepath.Join(tmpDir, "People")
+	if err := os.Mkdir(peopleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := 0; idx < 8; idx++ {
+		createTempFile(t, filepath.Join(peopleDir, fmt.Sprintf("portrait%d.jpg", idx)))
+	}
+	createAlbumMetadata(t, peopleDir, "sort: random\nseed: 7\n")
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	first, err := svc.GetCategory("People")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := svc.GetCategory("People")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(first.Images) != 8 {
+		t.Fatalf("expected 8 images, got %d", len(first.Images))
+	}
+	assertImageOrder(t, second, imageNames(first))
+
+	sorted := append([]string{}, imageNames(first)...)
+	sort.Strings(sorted)
+	if strings.Join(sorted, ",") == strings.Join(imageNames(first), ",") {
+		t.Errorf("expected seeded shuffle to differ from filename order, got %v", imageNames(first))
+	}
+}
+
 func TestFilesystemService_GetCategories_PeopleHasOneImage(t *testing.T) {
 	tmpDir := t.TempDir()
 	landscapeDir := filepath.Join(tmpDir, "Landscape")
@@ -417,3 +614,409 @@ func TestGetCategories_AlaskaHasAdventureGroup(t *testing.T) {
 		t.Errorf("expected Landscape group 'portfolio', got '%s'", landscapeCat.Group)
 	}
 }
+
+func TestFilesystemService_GetCategories_IncludesNestedAlbums(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"Alaska/2018", "Alaska/2019/June"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createTempFile(t, filepath.Join(tmpDir, "Alaska", "2018", "glacier.jpg"))
+	createTempFile(t, filepath.Join(tmpDir, "Alaska", "2019", "June", "bear.jpg"))
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	cats, err := svc.GetCategories()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	alaskaCat := findCategory(cats, "Alaska")
+	if alaskaCat == nil {
+		t.Fatal("expected Alaska category, not found")
+	}
+	if len(alaskaCat.Images) != 0 {
+		t.Errorf("expected no direct images in Alaska, got %d", len(alaskaCat.Images))
+	}
+	if len(alaskaCat.Albums) != 2 {
+		t.Fatalf("expected 2 albums in Alaska, got %d", len(alaskaCat.Albums))
+	}
+
+	june := alaskaCat.Albums[1].Albums
+	if len(june) != 1 || june[0].Path != "Alaska/2019/June" {
+		t.Fatalf("expected nested album Alaska/2019/June, got %+v", june)
+	}
+	if june[0].Group != "adventure" {
+		t.Errorf("expected nested album to inherit group 'adventure', got '%s'", june[0].Group)
+	}
+}
+
+func TestFilesystemService_GetCategories_CoverFallsBackToNestedAlbum(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"Alaska/2018", "Alaska/2019"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createTempFile(t, filepath.Join(tmpDir, "Alaska", "2018", "glacier.jpg"))
+	createTempFile(t, filepath.Join(tmpDir, "Alaska", "2019", "bear.jpg"))
+
+	svc := NewFilesystemService(tmpDir, "")
+
+	cat, err := svc.GetCategory("Alaska")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedCoverPath := filepath.Join("Alaska", "2019", "bear")
+	if cat.CoverImage.Path != expectedCoverPath {
+		t.Errorf("expected cover image path %s, got %s", expectedCoverPath, cat.CoverImage.Path)
+	}
+}
+
+func TestFilesystemService_GetCategory_ReturnsNestedAlbumWithBreadcrumbs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Alaska", "2019", "June"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createTempFile(t, filepath.Join(tmpDir, "Alaska", "2019", "June", "bear.jpg"))
+
+	svc := NewFilesystemService(tmpDir, "/assets/portfolio")
+
+	cat, err := svc.GetCategory("Alaska/2019/June")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cat.Name != "June" || cat.Path != "Alaska/2019/June" {
+		t.Errorf("expected album June at Alaska/2019/June, got %s at %s", cat.Name, cat.Path)
+	}
+
+	expectedParents := []Breadcrumb{
+		{Name: "Alaska", Path: "Alaska"},
+		{Name: "2019", Path: "Alaska/2019"},
+	}
+	if len(cat.Parents) != len(expectedParents) {
+		t.Fatalf("expected %d breadcrumbs, got %d", len(expectedParents), len(cat.Parents))
+	}
+	for idx, crumb := range expectedParents {
+		if cat.Parents[idx] != crumb {
+			t.Errorf("breadcrumb %d: expected %+v, got %+v", idx, crumb, cat.Parents[idx])
+		}
+	}
+
+	expectedPath := filepath.Join("/assets/portfolio", "Alaska", "2019", "June", "bear")
+	if len(cat.Images) != 1 || cat.Images[0].Path != expectedPath {
+		t.Errorf("expected image %s, got %+v", expectedPath, cat.Images)
+	}
+}
+
+func TestFilesystemService_GetCategory_RejectsTraversal(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Alaska", "2018"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewFilesystemService(filepath.Join(tmpDir, "Alaska"), "")
+
+	invalid := []string{"", "..", "2018/..", "2018/../..", "2018//", "/2018", "2018\\..", ".hidden"}
+	for _, name := range invalid {
+		if _, err := svc.GetCategory(name); err != ErrCategoryNotFound {
+			t.Errorf("GetCategory(%q): expected ErrCategoryNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestFilesystemService_GetCategory_SetsAlbumAndCaption(t *testing.T) {
+	tmpDir := t.TempDir()
+	juneDir := filepath.Join(tmpDir, "Alaska", "June")
+	if err := os.MkdirAll(juneDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createTempFile(t, filepath.Join(juneDir, "bear.jpg"))
+	createAlbumMetadata(t, juneDir, "photos:\n  bear.jpg:\n    caption: Brown bear at Brooks Falls\n    prints: [8x10, 12x18]\n")
+
+	cat, err := NewFilesystemService(tmpDir, "/assets/portfolio").GetCategory("Alaska/June")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	img := cat.Images[0]
+	if img.Album != "Alaska/June" {
+		t.Errorf("expected album Alaska/June, got %s", img.Album)
+	}
+	if img.Caption != "Brown bear at Brooks Falls" {
+		t.Errorf("expected caption from album metadata, got %q", img.Caption)
+	}
+	if img.Permalink() != "/portfolio/Alaska/June/bear" {
+		t.Errorf("expected permalink /portfolio/Alaska/June/bear, got %s", img.Permalink())
+	}
+	if len(img.PrintSizes) != 2 || img.PrintOrderURL() != "/prints/Alaska/June/bear" {
+		t.Errorf("expected prints in 8x10 and 12x18 at /prints/Alaska/June/bear, got %v at %s", img.PrintSizes, img.PrintOrderURL())
+	}
+}
+
+func TestFilesystemService_ListsPublishedWidthsAndFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	albumDir := filepath.Join(tmpDir, "Wildlife")
+	os.MkdirAll(albumDir, 0755)
+	for _, name := range []string{"bear.jpg", "bear_w600.jpg", "bear_w1600.jpg", "bear_w600_3x2.jpg", "bear_w600.webp", "bear_w1600.webp", "bear_w600.avif", "moose.jpg"} {
+		createTempFile(t, filepath.Join(albumDir, name))
+	}
+
+	cat, err := NewFilesystemService(tmpDir, "/assets/portfolio").GetCategory("Wildlife")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if len(cat.Images) != 2 {
+		t.Fatalf("expected variants not to be listed as photos, got %d images", len(cat.Images))
+	}
+	bear := cat.Images[0]
+	if formats := bear.Formats; len(formats) != 1 || formats[0] != "webp" {
+		t.Errorf("expected only formats with every published width, got %v", formats)
+	}
+	// Variant URLs carry the fingerprint of the file published at them.
+	version := func(name string) string {
+		hash, err := images.Fingerprint(filepath.Join(albumDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "?v=" + hash
+	}
+	view := bear.Responsive(images.ProseLayout)
+	if view.Src != "/assets/portfolio/Wildlife/bear_w1600.jpg"+version("bear_w1600.jpg") || view.SrcSet != "/assets/portfolio/Wildlife/bear_w600.jpg"+version("bear_w600.jpg")+" 600w, /assets/portfolio/Wildlife/bear_w1600.jpg"+version("bear_w1600.jpg")+" 1600w" {
+		t.Errorf("expected only the published widths, got %+v", view)
+	}
+	if len(view.Sources) != 1 || !strings.HasPrefix(view.Sources[0].SrcSet, "/assets/portfolio/Wildlife/bear_w600.webp"+version("bear_w600.webp")+" 600w") {
+		t.Errorf("expected fingerprinted WebP variants, got %+v", view.Sources)
+	}
+
+	moose := cat.Images[1]
+	if len(moose.Formats) != 0 {
+		t.Errorf("expected no modern formats for moose, got %v", moose.Formats)
+	}
+	if view := moose.Responsive(images.GridLayout); view.Src != "/assets/portfolio/Wildlife/moose.jpg" || view.SrcSet != "" {
+		t.Errorf("expected the photo itself without variants, got %+v", view)
+	}
+}
+
+func TestImage_ResponsiveCover_FallsBackWithoutCrops(t *testing.T) {
+	tmpDir := t.TempDir()
+	albumDir := filepath.Join(tmpDir, "Wildlife")
+	os.MkdirAll(albumDir, 0755)
+	for _, name := range []string{"bear.jpg", "bear_w600.jpg", "bear_w600_3x2.jpg", "moose.jpg", "moose_w600.jpg"} {
+		createTempFile(t, filepath.Join(albumDir, name))
+	}
+
+	cat, err := NewFilesystemService(tmpDir, "/assets/portfolio").GetCategory("Wildlife")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	bear := cat.Images[0].ResponsiveCover(images.CardLayout)
+	if !strings.HasPrefix(bear.Src, "/assets/portfolio/Wildlife/bear_w600_3x2.jpg?v=") || bear.SrcSet != bear.Src+" 600w" {
+		t.Errorf("expected only the published crop, got %+v", bear)
+	}
+	if moose := cat.Images[1].ResponsiveCover(images.CardLayout); !strings.HasPrefix(moose.Src, "/assets/portfolio/Wildlife/moose_w600.jpg?v=") {
+		t.Errorf("expected an uncropped cover without crops, got %+v", moose)
+	}
+}
+
+func TestFindPhoto(t *testing.T) {
+	images := []Image{
+		{Path: "/assets/portfolio/Wildlife/bear"},
+		{Path: "/assets/portfolio/Wildlife/eagle"},
+		{Path: "/assets/portfolio/Wildlife/moose"},
+	}
+
+	photo, prevPhoto, nextPhoto := FindPhoto(images, "eagle")
+	if photo == nil || photo.Slug() != "eagle" {
+		t.Fatalf("expected eagle, got %+v", photo)
+	}
+	if prevPhoto == nil || prevPhoto.Slug() != "bear" {
+		t.Errorf("expected previous photo bear, got %+v", prevPhoto)
+	}
+	if nextPhoto == nil || nextPhoto.Slug() != "moose" {
+		t.Errorf("expected next photo moose, got %+v", nextPhoto)
+	}
+
+	_, prevPhoto, _ = FindPhoto(images, "bear")
+	if prevPhoto != nil {
+		t.Errorf("expected no previous photo for the first image, got %+v", prevPhoto)
+	}
+	_, _, nextPhoto = FindPhoto(images, "moose")
+	if nextPhoto != nil {
+		t.Errorf("expected no next photo for the last image, got %+v", nextPhoto)
+	}
+
+	if photo, _, _ := FindPhoto(images, "missing"); photo != nil {
+		t.Errorf("expected nil for a missing photo, got %+v", photo)
+	}
+}
+
+func TestLoadMetadataPolicy_InheritsAlbumSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	albumDir := filepath.Join(tmpDir, "Alaska")
+	nestedDir := filepath.Join(albumDir, "2018")
+	os.MkdirAll(nestedDir, 0755)
+	policy, err := LoadMetadataPolicy(tmpDir, "Alaska/2018")
+	if err != nil || policy.GPS != images.GPSCoarse {
+		t.Errorf("expected coarse GPS unless exact is asked for, got %v, %v", policy.GPS, err)
+	}
+
+	createAlbumMetadata(t, albumDir, "location: exact\nmetadata: [Artist, Copyright]\n")
+	policy, err = LoadMetadataPolicy(tmpDir, "Alaska/2018")
+	if err != nil {
+		t.Fatalf("LoadMetadataPolicy failed: %v", err)
+	}
+	if policy.GPS != images.GPSExact {
+		t.Errorf("expected inherited exact GPS, got %v", policy.GPS)
+	}
+	if strings.Join(policy.Keep, ",") != "Artist,Copyright" {
+		t.Errorf("expected inherited metadata fields, got %v", policy.Keep)
+	}
+
+	createAlbumMetadata(t, nestedDir, "location: hidden\n")
+	policy, err = LoadMetadataPolicy(tmpDir, "Alaska/2018")
+	if err != nil {
+		t.Fatalf("LoadMetadataPolicy failed: %v", err)
+	}
+	if policy.GPS != images.GPSStrip {
+		t.Errorf("expected nested album to override GPS, got %v", policy.GPS)
+	}
+
+	if _, err := LoadMetadataPolicy(tmpDir, "../outside"); err != ErrCategoryNotFound {
+		t.Errorf("expected ErrCategoryNotFound for traversal, got %v", err)
+	}
+}
+
+func TestLoadWatermark_InheritsAlbumSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"Alaska/2018", "Alaska/Family"} {
+		os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+	}
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Alaska"), "watermark:\n  image: ../.branding/mark.png\n  position: top-left\n  opacity: 0.3\n")
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Alaska", "Family"), "watermark: {}\n")
+
+	watermark, err := Watermarks(tmpDir)("Alaska/2018/denali.jpg")
+	if err != nil {
+		t.Fatalf("LoadWatermark failed: %v", err)
+	}
+	want := images.Watermark{Image: filepath.Join(tmpDir, ".branding", "mark.png"), Position: images.WatermarkTopLeft, Opacity: 0.3}
+	if watermark != want {
+		t.Errorf("expected the inherited watermark %+v, got %+v", want, watermark)
+	}
+
+	if watermark, err := LoadWatermark(tmpDir, "Alaska/Family"); err != nil || watermark.Enabled() {
+		t.Errorf("expected an empty watermark to turn it off, got %+v, %v", watermark, err)
+	}
+	if watermark, err := LoadWatermark(tmpDir, ""); err != nil || watermark.Enabled() {
+		t.Errorf("expected no watermark by default, got %+v, %v", watermark, err)
+	}
+
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Alaska"), "watermark:\n  image: ../../secret.png\n")
+	if _, err := LoadWatermark(tmpDir, "Alaska"); err == nil {
+		t.Error("expected a watermark image outside the portfolio to be rejected")
+	}
+}
+
+func TestFocalPoints(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "Wildlife"), 0755)
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Wildlife"), "photos:\n  bear.jpg:\n    focus: [0.25, 0.4]\n  moose.jpg:\n    focus: [2, 0.5]\n")
+
+	focusFor := FocalPoints(tmpDir)
+	if focus, err := focusFor("Wildlife/bear.jpg"); err != nil || focus == nil || *focus != (images.FocalPoint{X: 0.25, Y: 0.4}) {
+		t.Errorf("expected the focus from album metadata, got %v, %v", focus, err)
+	}
+	for _, relPath := range []string{"Wildlife/moose.jpg", "Wildlife/eagle.jpg"} {
+		if focus, err := focusFor(relPath); err != nil || focus != nil {
+			t.Errorf("%s: expected no focus to estimate one instead, got %v, %v", relPath, focus, err)
+		}
+	}
+}
+
+func TestFilesystemService_PrivateAlbumsAreHiddenFromListings(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"Landscape/Proofs", "Clients/Smith"} {
+		os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+	}
+	createTempFile(t, filepath.Join(tmpDir, "Landscape", "lake.jpg"))
+	createTempFile(t, filepath.Join(tmpDir, "Landscape", "Proofs", "draft.jpg"))
+	createTempFile(t, filepath.Join(tmpDir, "Clients", "Smith", "wedding.jpg"))
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Clients"), "private: true\npassword_hash: hashed\n")
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Landscape", "Proofs"), "private: true\n")
+
+	service := NewFilesystemService(tmpDir, "/assets")
+
+	categories, err := service.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	if len(categories) != 1 || categories[0].Name != "Landscape" {
+		t.Fatalf("expected only Landscape to be listed, got %v", categories)
+	}
+	if len(categories[0].Albums) != 0 {
+		t.Errorf("expected the private nested album to be hidden, got %v", categories[0].Albums)
+	}
+	if portfolioCats, adventureCats := GroupCategories([]Category{{Name: "Clients", Access: Access{Album: "Clients"}}}); len(portfolioCats)+len(adventureCats) != 0 {
+		t.Error("expected GroupCategories to leave out private categories")
+	}
+
+	clients, err := service.GetCategory("Clients")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if clients.Access != (Access{Album: "Clients", PasswordHash: "hashed"}) {
+		t.Errorf("unexpected access %+v", clients.Access)
+	}
+	// Albums inside a private category share its lock and stay listed there.
+	if len(clients.Albums) != 1 || clients.Albums[0].Access.Album != "Clients" {
+		t.Errorf("expected Smith to inherit the Clients lock, got %+v", clients.Albums)
+	}
+
+	smith, err := service.GetCategory("Clients/Smith")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if smith.Access.Album != "Clients" {
+		t.Errorf("expected Smith to inherit the Clients lock, got %+v", smith.Access)
+	}
+}
+
+func TestFilesystemService_DownloadableIsInherited(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"Trips/Day 1", "Trips/Outtakes", "Landscape"} {
+		os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+		createTempFile(t, filepath.Join(tmpDir, dir, "photo.jpg"))
+	}
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Trips"), "downloadable: true\n")
+	createAlbumMetadata(t, filepath.Join(tmpDir, "Trips", "Outtakes"), "downloadable: false\n")
+
+	service := NewFilesystemService(tmpDir, "/assets")
+
+	trips, err := service.GetCategory("Trips")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if !trips.Downloadable {
+		t.Error("expected Trips to be downloadable")
+	}
+	for _, album := range trips.Albums {
+		if album.Downloadable != (album.Name == "Day 1") {
+			t.Errorf("%s: unexpected Downloadable %v", album.Name, album.Downloadable)
+		}
+	}
+
+	landscape, err := service.GetCategory("Landscape")
+	if err != nil {
+		t.Fatalf("GetCategory failed: %v", err)
+	}
+	if landscape.Downloadable {
+		t.Error("expected albums to be downloadable only when they opt in")
+	}
+}
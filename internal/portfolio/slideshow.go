@@ -0,0 +1,11 @@
+package portfolio
+
+// Playlist returns the images of the given categories and all of their nested
+// albums, each album's own photos before those of its nested albums.
+func Playlist(categories []Category) []Image {
+	var playlist []Image
+	walkAlbums(categories, func(album Category) {
+		playlist = append(playlist, album.Images...)
+	})
+	return playlist
+}
@@ -0,0 +1,27 @@
+package portfolio
+
+import "testing"
+
+func TestPlaylist(t *testing.T) {
+	categories := []Category{
+		{
+			Name:   "Alaska",
+			Images: []Image{{Path: "/a/denali"}},
+			Albums: []Category{
+				{Name: "2018", Images: []Image{{Path: "/a/2018/bear"}, {Path: "/a/2018/moose"}}},
+			},
+		},
+		{Name: "Wildlife", Images: []Image{{Path: "/w/eagle"}}},
+	}
+
+	playlist := Playlist(categories)
+	want := []string{"/a/denali", "/a/2018/bear", "/a/2018/moose", "/w/eagle"}
+	if len(playlist) != len(want) {
+		t.Fatalf("expected %d images, got %d", len(want), len(playlist))
+	}
+	for idx, path := range want {
+		if playlist[idx].Path != path {
+			t.Errorf("position %d: expected %s, got %s", idx, path, playlist[idx].Path)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package components
+
+// galleryScript registers the Alpine component shared by every lightbox gallery.
+// It reads the data written by galleryData.
+const galleryScript = `<script>
+document.addEventListener('alpine:init', () => {
+    Alpine.data('gallery', () => ({
+        images: window.categoryData.images,
+        views: window.categoryData.views,
+        photoToBlog: window.categoryData.photoToBlog,
+        lightboxOpen: false,
+        lightboxIndex: 0,
+        activeTag: '',
+
+        get lightboxImage() {
+            return this.images[this.lightboxIndex] || {};
+        },
+
+        get lightboxView() {
+            return this.views[this.lightboxIndex] || {};
+        },
+
+        // The lightbox mirrors the open photo's permalink in the address bar so it can be shared.
+        init() {
+            this.pageURL = window.location.pathname;
+            window.addEventListener('popstate', () => {
+                const index = this.images.findIndex((img) => this.permalink(img) === window.location.pathname);
+                if (index >= 0) {
+                    this.lightboxIndex = index;
+                    this.show();
+                } else {
+                    this.hide();
+                }
+            });
+        },
+
+        permalink(img) {
+            return '/portfolio/' + img.Album + '/' + img.Path.split('/').pop();
+        },
+
+        show() {
+            this.lightboxOpen = true;
+            document.body.style.overflow = 'hidden';
+        },
+
+        hide() {
+            this.lightboxOpen = false;
+            document.body.style.overflow = '';
+        },
+
+        openLightbox(index) {
+            this.lightboxIndex = index;
+            this.show();
+            history.pushState({ lightbox: true }, '', this.permalink(this.lightboxImage));
+        },
+
+        closeLightbox() {
+            this.hide();
+            if (history.state && history.state.lightbox) {
+                history.back();
+            }
+        },
+
+        matches(index) {
+            return !this.activeTag || (this.images[index].Keywords || []).includes(this.activeTag);
+        },
+
+        // step moves through the images hidden by neither the active tag filter nor anything else.
+        step(delta) {
+            for (let i = 0; i < this.images.length; i++) {
+                this.lightboxIndex = (this.lightboxIndex + delta + this.images.length) % this.images.length;
+                if (this.matches(this.lightboxIndex)) {
+                    history.replaceState({ lightbox: true }, '', this.permalink(this.lightboxImage));
+                    return;
+                }
+            }
+        },
+
+        nextImage() {
+            this.step(1);
+        },
+
+        prevImage() {
+            this.step(-1);
+        }
+    }));
+});
+</script>`
+
+// mapScript clusters the features of /api/photos.geojson on the #photo-map element.
+// Clicking a marker dispatches "open-photo" with the feature's gallery index.
+const mapScript = `<script>
+document.addEventListener('DOMContentLoaded', () => {
+    const map = L.map('photo-map').setView([61.2, -149.9], 4);
+    L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
+        maxZoom: 18,
+        attribution: '&copy; OpenStreetMap contributors'
+    }).addTo(map);
+
+    fetch('/api/photos.geojson')
+        .then((response) => response.json())
+        .then((data) => {
+            const clusters = L.markerClusterGroup();
+            const layer = L.geoJSON(data, {
+                onEachFeature: (feature, marker) => {
+                    marker.bindTooltip('<img src="' + feature.properties.thumbnail + '" class="w-32" alt="">');
+                    marker.on('click', () => {
+                        window.dispatchEvent(new CustomEvent('open-photo', { detail: feature.properties.index }));
+                    });
+                }
+            });
+            clusters.addLayer(layer);
+            map.addLayer(clusters);
+            if (data.features.length > 0) {
+                map.fitBounds(clusters.getBounds(), { padding: [40, 40] });
+            }
+        });
+});
+</script>`
+
+// slideshowScript registers the Alpine component behind SlideshowPage. It reads
+// the data the page writes to window.slideshowData. Shuffled slideshows get a
+// new order on every pass.
+const slideshowScript = `<script>
+document.addEventListener('alpine:init', () => {
+    Alpine.data('slideshow', () => ({
+        images: window.slideshowData.images,
+        views: window.slideshowData.views,
+        captions: window.slideshowData.captions,
+        order: [],
+        position: 0,
+        paused: false,
+        fading: false,
+        idle: false,
+        idleTimer: null,
+
+        init() {
+            this.order = this.sequence();
+            this.preload();
+            setInterval(() => {
+                if (!this.paused) {
+                    this.advance(1);
+                }
+            }, window.slideshowData.interval);
+            this.wake();
+        },
+
+        get current() {
+            return this.images[this.order[this.position]] || {};
+        },
+
+        get view() {
+            return this.views[this.order[this.position]] || {};
+        },
+
+        sequence() {
+            const order = this.images.map((_, index) => index);
+            if (window.slideshowData.shuffle) {
+                for (let i = order.length - 1; i > 0; i--) {
+                    const j = Math.floor(Math.random() * (i + 1));
+                    [order[i], order[j]] = [order[j], order[i]];
+                }
+            }
+            return order;
+        },
+
+        // preload fetches the photo after the current one so it shows without a gap.
+        preload() {
+            const next = this.views[this.order[(this.position + 1) % this.order.length]];
+            if (next) {
+                const img = new Image();
+                img.sizes = next.sizes;
+                img.srcset = next.srcset;
+                img.src = next.src;
+            }
+        },
+
+        advance(delta) {
+            if (this.images.length < 2) {
+                return;
+            }
+            const shown = this.order[this.position];
+            this.fading = true;
+            setTimeout(() => {
+                this.position += delta;
+                if (this.position >= this.order.length) {
+                    this.order = this.sequence();
+                    this.position = 0;
+                } else if (this.position < 0) {
+                    this.position = this.order.length - 1;
+                }
+                // The same photo again fires no load event to fade it back in.
+                if (this.order[this.position] === shown) {
+                    this.fading = false;
+                }
+                this.preload();
+            }, 700);
+        },
+
+        // wake shows the controls and cursor until the mouse rests for a few seconds.
+        wake() {
+            this.idle = false;
+            clearTimeout(this.idleTimer);
+            this.idleTimer = setTimeout(() => { this.idle = true; }, 3000);
+        },
+
+        fullscreen() {
+            if (document.documentElement.requestFullscreen) {
+                document.documentElement.requestFullscreen();
+            }
+        }
+    }));
+});
+</script>`
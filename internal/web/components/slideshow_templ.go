@@ -0,0 +1,86 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.1001
+package components
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+import "fmt"
+import "personalwebsite/internal/images"
+import "personalwebsite/internal/portfolio"
+
+// SlideshowPage plays photos full screen for exhibition screens, without the
+// site chrome. The next photo is preloaded while one is shown.
+func SlideshowPage(title string, photos []portfolio.Image, intervalMillis int64, shuffle bool, captions bool) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\" class=\"h-full bg-black\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(title)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/web/components/slideshow.templ`, Line: 15, Col: 17}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " | Slideshow</title><link href=\"/assets/css/output.css\" rel=\"stylesheet\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templ.Raw(fmt.Sprintf(`<script>window.slideshowData = { images: %s, views: %s, interval: %d, shuffle: %t, captions: %t };</script>`, ToJSON(photos), ToJSON(responsiveViews(photos, images.FullLayout)), intervalMillis, shuffle, captions)).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templ.Raw(slideshowScript).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<script defer src=\"https://cdn.jsdelivr.net/npm/alpinejs@3.x.x/dist/cdn.min.js\"></script></head><body class=\"h-full overflow-hidden bg-black text-white\"><div x-data=\"slideshow\" class=\"relative h-full w-full flex items-center justify-center\" :class=\"idle ? 'cursor-none' : ''\" @mousemove.window=\"wake()\" @keydown.arrow-right.window=\"advance(1)\" @keydown.arrow-left.window=\"advance(-1)\" @keydown.space.window.prevent=\"paused = !paused\" @keydown.f.window=\"fullscreen()\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if len(photos) == 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<p class=\"uppercase tracking-widest text-sm text-silver-400\">No photos to show.</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<img :src=\"view.src\" :srcset=\"view.srcset\" :sizes=\"view.sizes\" :alt=\"current.Caption\" @load=\"fading = false\" class=\"max-w-full max-h-full object-contain transition-opacity duration-700\" :class=\"fading ? 'opacity-0' : 'opacity-100'\"><div x-show=\"captions && (current.Caption || current.Album)\" class=\"absolute bottom-8 left-8 max-w-xl bg-black/50 backdrop-blur px-4 py-3 transition-opacity duration-700\" :class=\"fading ? 'opacity-0' : 'opacity-100'\"><p x-show=\"current.Caption\" x-text=\"current.Caption\" class=\"text-xl font-serif\"></p><p x-text=\"current.Album\" class=\"text-xs uppercase tracking-widest text-silver-400\"></p></div><div x-show=\"!idle\" x-transition.opacity class=\"absolute top-6 right-6 flex gap-4 text-xs uppercase tracking-widest text-silver-400\"><span x-show=\"paused\">Paused</span> <button @click=\"fullscreen()\" class=\"hover:text-white\">Full Screen</button></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div></body></html>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
@@ -3,6 +3,8 @@ package components
 import (
 	"bytes"
 	"encoding/json"
+	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
 )
 
 func ToJSON(v any) string {
@@ -15,3 +17,21 @@ func ToJSON(v any) string {
 	// Encode adds a newline at the end, trim it
 	return string(bytes.TrimSpace(buf.Bytes()))
 }
+
+// categoryRoot returns the path of the top-level category an album belongs to.
+func categoryRoot(category portfolio.Category) string {
+	if len(category.Parents) > 0 {
+		return category.Parents[0].Path
+	}
+	return category.Path
+}
+
+// responsiveViews describes each photo laid out as layout, in order, for
+// scripts that swap photos in and out of one <img>.
+func responsiveViews(photos []portfolio.Image, layout images.Layout) []images.Responsive {
+	views := make([]images.Responsive, len(photos))
+	for i, photo := range photos {
+		views[i] = photo.Responsive(layout)
+	}
+	return views
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"archive/zip"
+	"mime"
+	"net/http"
+	"personalwebsite/internal/portfolio"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxConcurrentDownloads bounds archive downloads when ServerConfig leaves it unset.
+const defaultMaxConcurrentDownloads = 2
+
+// albumDownloads streams albums as ZIP archives, a few at a time so that large
+// galleries cannot starve the server.
+type albumDownloads struct {
+	images *ImageHandler
+	slots  chan struct{}
+}
+
+func newAlbumDownloads(images *ImageHandler, limit int) *albumDownloads {
+	if limit <= 0 {
+		limit = defaultMaxConcurrentDownloads
+	}
+	return &albumDownloads{images: images, slots: make(chan struct{}, limit)}
+}
+
+// archiveEntry is a photo's name inside the archive and its path under the portfolio root.
+type archiveEntry struct {
+	name    string
+	relPath string
+}
+
+// archiveEntries lists the album's photos followed by those of its downloadable
+// nested albums, which go in folders named after them.
+func archiveEntries(album portfolio.Category, folder string) []archiveEntry {
+	var entries []archiveEntry
+	for _, img := range album.Images {
+		entries = append(entries, archiveEntry{
+			name:    folder + img.FileName(),
+			relPath: strings.TrimPrefix(img.Album+"/"+img.FileName(), "/"),
+		})
+	}
+	for _, child := range album.Albums {
+		if child.Downloadable {
+			entries = append(entries, archiveEntries(child, folder+child.Name+"/")...)
+		}
+	}
+	return entries
+}
+
+// serve writes the album as a ZIP of full-size images, or of the width given by
+// the "w" query parameter. Photos are written one at a time straight to the
+// response, so the archive is never held in memory.
+func (d *albumDownloads) serve(writer http.ResponseWriter, request *http.Request, album portfolio.Category) {
+	width := 0
+	if widthStr := request.URL.Query().Get("w"); widthStr != "" {
+		var err error
+		width, err = strconv.Atoi(widthStr)
+		if _, ok := pipeline.Variant(width, nil); err != nil || !ok {
+			http.Error(writer, "Unsupported width", http.StatusBadRequest)
+			return
+		}
+	}
+
+	select {
+	case d.slots <- struct{}{}:
+		defer func() { <-d.slots }()
+	default:
+		writer.Header().Set("Retry-After", "30")
+		http.Error(writer, "Too many downloads in progress, please try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": album.Name + ".zip"}))
+	if album.Access.IsPrivate() {
+		writer.Header().Set("Cache-Control", "private")
+	}
+
+	archive := zip.NewWriter(writer)
+	for _, entry := range archiveEntries(album, "") {
+		data, err := d.images.publishedImage(entry.relPath, width)
+		if err != nil {
+			// Headers are already sent; a truncated archive is all we can report.
+			return
+		}
+		// Photos are already compressed, so store them as they are.
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Store})
+		if err != nil {
+			return
+		}
+		if _, err := file.Write(data); err != nil {
+			return
+		}
+	}
+	archive.Close()
+}
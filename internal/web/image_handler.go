@@ -1,34 +1,48 @@
 package web
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"personalwebsite/internal/access"
+	"personalwebsite/internal/config"
 	"personalwebsite/internal/images"
+	"personalwebsite/internal/portfolio"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ImageHandler struct {
 	contentRoot string
 	resizer     *images.Resizer
+	sessions    *access.Sessions
 }
 
-func NewImageHandler(contentRoot string) *ImageHandler {
-	cacheRoot := os.Getenv("CACHE_DIR")
-	if cacheRoot == "" {
-		cacheRoot = filepath.Join(os.TempDir(), "personalwebsite_cache")
-	}
+func NewImageHandler(contentRoot string, sessions *access.Sessions) *ImageHandler {
+	cacheRoot := config.ResolveCacheRoot()
 	os.MkdirAll(cacheRoot, 0755)
+	opts := []images.ResizerOption{
+		images.WithWatermarks(portfolio.Watermarks(contentRoot)),
+		images.WithFocalPoints(portfolio.FocalPoints(contentRoot)),
+	}
+	// Without an index the cache still works; it just isn't kept in budget.
+	cache, err := images.OpenCache(cacheRoot, config.CacheMaxBytes())
+	if err == nil {
+		opts = append(opts, images.WithCache(cache))
+	}
 	return &ImageHandler{
 		contentRoot: contentRoot,
-		resizer:     images.NewResizer(contentRoot, cacheRoot),
+		resizer:     images.NewResizer(contentRoot, cacheRoot, opts...),
+		sessions:    sessions,
 	}
 }
 
-// allowedWidths restricts resize operations to the specific widths we actually use.
-// This prevents DoS via arbitrary resize requests on large images.
-var allowedWidths = map[int]bool{600: true, 1200: true, 1600: true}
+// pipeline declares the only variants the handler makes. Restricting resizes
+// to them prevents DoS via arbitrary resize requests on large images.
+var pipeline = images.DefaultPipeline
 
 func (h *ImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	relPath := strings.TrimPrefix(r.URL.Path, "/")
@@ -61,8 +75,32 @@ func (h *ImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sidecars are a metadata source and may hold locations the policy strips;
+	// album settings hold password hashes; dot files such as the optimizer's
+	// manifest list every photo, private ones included.
+	base := filepath.Base(relPath)
+	if strings.EqualFold(filepath.Ext(relPath), ".xmp") || base == portfolio.AlbumMetadataFile || strings.HasPrefix(base, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Images in private albums need an unlocked session.
+	albumAccess, err := portfolio.LoadAccess(h.contentRoot, filepath.ToSlash(filepath.Dir(relPath)))
+	if err != nil || (albumAccess.IsPrivate() && !h.sessions.Allowed(r, albumAccess.Album)) {
+		http.NotFound(w, r)
+		return
+	}
+	if albumAccess.IsPrivate() {
+		w.Header().Set("Cache-Control", "private")
+		w.Header().Add("Vary", "Cookie")
+	}
+
 	// Check if file exists
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		// Variants are prebuilt by cmd/optimize; until then, make them on demand.
+		if h.serveMissingVariant(w, r, relPath) {
+			return
+		}
 		http.NotFound(w, r)
 		return
 	}
@@ -70,24 +108,268 @@ func (h *ImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Parse width query param
 	widthStr := r.URL.Query().Get("w")
 	if widthStr == "" {
-		// Serve original file
-		http.ServeFile(w, r, fullPath)
+		// Prebuilt variants may also have been published in a modern format.
+		if images.IsVariantName(filepath.Base(relPath)) && h.serveModern(w, r, fullPath, nil) {
+			return
+		}
+		h.serveFullSize(w, r, relPath, fullPath)
 		return
 	}
 
 	width, err := strconv.Atoi(widthStr)
-	if err != nil || !allowedWidths[width] {
-		// Invalid or non-whitelisted width, serve original
-		http.ServeFile(w, r, fullPath)
+	if err != nil {
+		h.serveFullSize(w, r, relPath, fullPath)
 		return
 	}
+	var aspect *images.AspectRatio
+	if ratio := r.URL.Query().Get("ar"); ratio != "" {
+		parsed, err := images.ParseAspectRatio(ratio)
+		if err != nil {
+			h.serveFullSize(w, r, relPath, fullPath)
+			return
+		}
+		aspect = &parsed
+	}
+	spec, ok := pipeline.Variant(width, aspect)
+	if !ok {
+		// Not a published variant, serve the full-size image
+		h.serveFullSize(w, r, relPath, fullPath)
+		return
+	}
+	h.serveVariant(w, r, relPath, spec)
+}
 
-	cachedPath, err := h.resizer.Resize(relPath, width)
+// serveVariant serves the variant of the image at relPath that spec describes.
+// A variant that cannot be made is an error rather than the original, which
+// would skip its watermark.
+func (h *ImageHandler) serveVariant(w http.ResponseWriter, r *http.Request, relPath string, spec images.VariantSpec) {
+	cachedPath, err := h.resizer.Render(relPath, spec)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if !h.serveModern(w, r, cachedPath, spec.Formats) {
+		h.serveFile(w, r, cachedPath)
+	}
+}
+
+// fullWidth is the width the image at relPath is handed out at in full: 0 for
+// the original, or the widest uncropped variant when its album is watermarked,
+// so the unmarked original never leaves the server.
+func (h *ImageHandler) fullWidth(relPath string) (int, error) {
+	if images.IsVariantName(filepath.Base(relPath)) {
+		return 0, nil
+	}
+	watermark, err := portfolio.LoadWatermark(h.contentRoot, filepath.ToSlash(filepath.Dir(relPath)))
+	if err != nil {
+		return 0, err
+	}
+	widths := pipeline.Ladder(nil).Widths
+	if len(widths) == 0 || !watermark.AppliesTo(widths[len(widths)-1]) {
+		return 0, nil
+	}
+	return widths[len(widths)-1], nil
+}
+
+// serveFullSize serves the image at relPath at its fullWidth.
+func (h *ImageHandler) serveFullSize(w http.ResponseWriter, r *http.Request, relPath, fullPath string) {
+	width, err := h.fullWidth(relPath)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if width == 0 {
+		h.serveOriginal(w, r, relPath, fullPath)
+		return
+	}
+	h.serveVariant(w, r, relPath, pipeline.Spec(width, nil))
+}
+
+// serveModern serves the image at path in the most preferred modern format the
+// browser accepts, reporting whether it did. Missing copies in the formats
+// listed in encode are encoded if the encoder is installed; otherwise only
+// published copies are used.
+func (h *ImageHandler) serveModern(w http.ResponseWriter, r *http.Request, path string, encode []images.Format) bool {
+	var candidates []images.Format
+	for _, format := range images.ModernFormats {
+		if _, err := os.Stat(format.Sibling(path)); err == nil || (canEncode(encode, format) && format.Available()) {
+			candidates = append(candidates, format)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	w.Header().Add("Vary", "Accept")
+
+	format, ok := images.NegotiateFormat(r.Header.Get("Accept"), candidates)
+	if !ok {
+		return false
+	}
+	sibling := format.Sibling(path)
+	if canEncode(encode, format) {
+		var err error
+		if sibling, err = h.resizer.EncodeSibling(path, format); err != nil {
+			return false
+		}
+	}
+	w.Header().Set("Content-Type", format.MIMEType)
+	h.serveFile(w, r, sibling)
+	return true
+}
+
+// canEncode reports whether format is one of formats.
+func canEncode(formats []images.Format, format images.Format) bool {
+	for _, candidate := range formats {
+		if candidate.Name == format.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// serveMissingVariant serves a missing prebuilt variant such as "DSC01_w1200.jpg"
+// or "DSC01_w600_3x2.jpg" by resizing its source image. It reports whether
+// relPath named one.
+func (h *ImageHandler) serveMissingVariant(w http.ResponseWriter, r *http.Request, relPath string) bool {
+	dir, name := filepath.Split(relPath)
+	source, width, aspect, ok := images.ParseVariantName(name)
+	if !ok {
+		return false
+	}
+	spec, ok := pipeline.Variant(width, aspect)
+	if !ok {
+		return false
+	}
+	sourcePath := filepath.Join(dir, source)
+	if _, err := os.Stat(filepath.Join(h.contentRoot, sourcePath)); err != nil {
+		return false
+	}
+
+	h.serveVariant(w, r, sourcePath, spec)
+	return true
+}
+
+// serveOriginal serves a file from the content root with its metadata filtered
+// by the album's policy. Prebuilt variants never carry a location.
+func (h *ImageHandler) serveOriginal(w http.ResponseWriter, r *http.Request, relPath, fullPath string) {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		h.serveFile(w, r, fullPath)
+		return
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	published, err := h.publishedOriginal(relPath)
+	if err != nil {
+		if err == portfolio.ErrCategoryNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.serveContent(w, r, filepath.Base(relPath), info.ModTime(), bytes.NewReader(published), images.FingerprintData(published))
+}
+
+// fingerprintedCacheControl is sent for requests that name the fingerprint of
+// the prebuilt variant they want, e.g. "DSC01_w600.jpg?v=1f2e3d4c5b6a7988", as
+// pages link them. A re-published variant has a new fingerprint and so a new
+// URL, so browsers may keep these for good.
+const fingerprintedCacheControl = "max-age=31536000, immutable"
+
+// serveContent serves an image with a strong ETag from its content hash, which
+// http.ServeContent checks If-None-Match against to answer 304 Not Modified.
+// Requests without a matching fingerprint must revalidate before reuse.
+func (h *ImageHandler) serveContent(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, content io.ReadSeeker, hash string) {
+	// Private albums have already set "private" to keep shared caches out.
+	scope := w.Header().Get("Cache-Control")
+	if scope == "" {
+		scope = "public"
+	}
+	if h.fingerprinted(r) {
+		w.Header().Set("Cache-Control", scope+", "+fingerprintedCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", scope+", no-cache")
+	}
+	w.Header().Set("ETag", `"`+hash+`"`)
+	http.ServeContent(w, r, name, modTime, content)
+}
+
+// fingerprinted reports whether r asks for a prebuilt variant by the
+// images.Fingerprint of the file published at its path. Whatever is served for
+// the path, such as a WebP copy, is re-published along with that file. Resized
+// and full-size requests depend on album settings as well, so never qualify.
+func (h *ImageHandler) fingerprinted(r *http.Request) bool {
+	query := r.URL.Query()
+	relPath := strings.TrimPrefix(r.URL.Path, "/")
+	if query.Get("v") == "" || query.Has("w") || !images.IsVariantName(filepath.Base(relPath)) {
+		return false
+	}
+	hash, err := images.Fingerprint(filepath.Join(h.contentRoot, relPath))
+	return err == nil && hash == query.Get("v")
+}
+
+// serveFile serves a file as it is on disk, hashing it only when it changes.
+func (h *ImageHandler) serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	file, err := os.Open(path)
 	if err != nil {
-		// Failed to resize, serve original as fallback
-		http.ServeFile(w, r, fullPath)
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
 		return
 	}
 
-	http.ServeFile(w, r, cachedPath)
+	hash, err := images.FingerprintFile(file)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	h.serveContent(w, r, info.Name(), info.ModTime(), file, hash)
+}
+
+// publishedOriginal returns the image at relPath with its metadata filtered by
+// the album's policy.
+func (h *ImageHandler) publishedOriginal(relPath string) ([]byte, error) {
+	policy, err := portfolio.LoadMetadataPolicy(h.contentRoot, filepath.ToSlash(filepath.Dir(relPath)))
+	if err != nil {
+		return nil, err
+	}
+	if images.IsVariantName(filepath.Base(relPath)) {
+		policy = policy.ForVariant()
+	}
+
+	data, err := os.ReadFile(filepath.Join(h.contentRoot, relPath))
+	if err != nil {
+		return nil, err
+	}
+	return policy.RewriteMetadata(data)
+}
+
+// publishedImage returns the image at relPath as it is served: the full-size
+// image when width is 0, otherwise the resized variant.
+func (h *ImageHandler) publishedImage(relPath string, width int) ([]byte, error) {
+	if width == 0 {
+		full, err := h.fullWidth(relPath)
+		if err != nil {
+			return nil, err
+		}
+		if full == 0 {
+			return h.publishedOriginal(relPath)
+		}
+		width = full
+	}
+	cachedPath, err := h.resizer.Resize(relPath, width)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(cachedPath)
 }
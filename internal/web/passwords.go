@@ -0,0 +1,35 @@
+package web
+
+import (
+	"net/http"
+	"personalwebsite/internal/access"
+)
+
+// maxConcurrentPasswordChecks bounds the password checks run at once. Each
+// hashes the guess with PBKDF2, which keeps a core busy for a noticeable part
+// of a second, so a flood of guesses could otherwise take the whole server.
+const maxConcurrentPasswordChecks = 2
+
+// passwordChecks runs access.CheckPassword a few at a time for album unlocks
+// and admin sign-ins.
+type passwordChecks struct {
+	slots chan struct{}
+}
+
+func newPasswordChecks(limit int) *passwordChecks {
+	return &passwordChecks{slots: make(chan struct{}, limit)}
+}
+
+// check reports whether password matches hash. When too many checks are
+// already running it answers 503 instead and reports ok false.
+func (p *passwordChecks) check(writer http.ResponseWriter, hash, password string) (matched, ok bool) {
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	default:
+		writer.Header().Set("Retry-After", "5")
+		http.Error(writer, "Too many sign-in attempts in progress, please try again shortly", http.StatusServiceUnavailable)
+		return false, false
+	}
+	return access.CheckPassword(hash, password), true
+}
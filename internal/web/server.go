@@ -1,21 +1,49 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
+	"personalwebsite/internal/access"
 	"personalwebsite/internal/blog"
+	"personalwebsite/internal/orders"
 	"personalwebsite/internal/portfolio"
 	"personalwebsite/internal/web/components"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ServerConfig struct {
 	PortfolioAssetsPath string
 	AboutmeAssetsPath   string
 	CSSAssetsPath       string
+	// SessionSecret signs the cookies that unlock private albums. When empty a
+	// random secret is used and visitors must unlock again after a restart.
+	SessionSecret []byte
+	// MaxConcurrentDownloads caps how many album archives stream at once.
+	// Zero means defaultMaxConcurrentDownloads.
+	MaxConcurrentDownloads int
+	// OrdersPath is the JSON Lines file print requests are appended to.
+	OrdersPath string
+	// AdminPasswordHash, from `cmd/access hash`, guards /admin pages. When empty
+	// they are not served at all.
+	AdminPasswordHash string
+	// Slideshow sets how slideshows play. The zero value means DefaultSlideshowConfig.
+	Slideshow SlideshowConfig
 }
 
 func NewServer(blogService blog.Service, portfolioService portfolio.Service, serverConfig ServerConfig) http.Handler {
 	mux := http.NewServeMux()
+	sessions := access.NewSessions(serverConfig.SessionSecret)
+	shareLinks := access.NewShareLinks(serverConfig.SessionSecret)
+	imageHandler := NewImageHandler(serverConfig.PortfolioAssetsPath, sessions)
+	downloads := newAlbumDownloads(imageHandler, serverConfig.MaxConcurrentDownloads)
+	passwords := newPasswordChecks(maxConcurrentPasswordChecks)
+	orderStore := orders.NewFileStore(serverConfig.OrdersPath)
+	slideshowConfig := serverConfig.Slideshow
+	if slideshowConfig == (SlideshowConfig{}) {
+		slideshowConfig = DefaultSlideshowConfig
+	}
 	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
 		if request.URL.Path != "/" {
 			http.NotFound(writer, request)
@@ -40,11 +68,53 @@ func NewServer(blogService blog.Service, portfolioService portfolio.Service, ser
 		components.Portfolio(portfolioCats, adventureCats, photoToBlog).Render(request.Context(), writer)
 	})
 
-	mux.HandleFunc("GET /portfolio/{category}", func(writer http.ResponseWriter, request *http.Request) {
-		categoryName := request.PathValue("category")
-		category, err := portfolioService.GetCategory(categoryName)
+	// Plays every public album; a category named "slideshow" would be hidden by it.
+	mux.HandleFunc("GET /portfolio/slideshow", func(writer http.ResponseWriter, request *http.Request) {
+		categories, err := portfolioService.GetCategories()
+		if err != nil {
+			http.Error(writer, "Failed to load portfolio categories", http.StatusInternalServerError)
+			return
+		}
+
+		serveSlideshow(writer, request, slideshowConfig, "Portfolio", portfolio.Playlist(categories))
+	})
+
+	mux.HandleFunc("GET /portfolio/tags/{keyword}", func(writer http.ResponseWriter, request *http.Request) {
+		categories, err := portfolioService.GetCategories()
+		if err != nil {
+			http.Error(writer, "Failed to load portfolio categories", http.StatusInternalServerError)
+			return
+		}
+
+		keyword := strings.ToLower(request.PathValue("keyword"))
+		tagged := portfolio.IndexKeywords(categories)[keyword]
+		if len(tagged) == 0 {
+			http.NotFound(writer, request)
+			return
+		}
+
+		components.TagPage(keyword, tagged, loadPhotoToBlog(blogService)).Render(request.Context(), writer)
+	})
+
+	mux.Handle("GET /portfolio/{path...}", withShareLinks(sessions, shareLinks, func(writer http.ResponseWriter, request *http.Request) {
+		albumPath := strings.TrimSuffix(request.PathValue("path"), "/")
+		category, err := portfolioService.GetCategory(albumPath)
 		if err != nil {
 			if err == portfolio.ErrCategoryNotFound {
+				// Not an album: the last segment may ask for an archive or
+				// slideshow of its parent album or name a photo in it.
+				if parentPath, ok := strings.CutSuffix(albumPath, "/download"); ok && parentPath != "" {
+					serveDownload(writer, request, portfolioService, sessions, downloads, parentPath)
+					return
+				}
+				if parentPath, ok := strings.CutSuffix(albumPath, "/slideshow"); ok && parentPath != "" {
+					serveAlbumSlideshow(writer, request, portfolioService, sessions, slideshowConfig, parentPath)
+					return
+				}
+				if idx := strings.LastIndex(albumPath, "/"); idx > 0 {
+					servePhoto(writer, request, portfolioService, blogService, sessions, albumPath[:idx], albumPath[idx+1:])
+					return
+				}
 				http.NotFound(writer, request)
 				return
 			}
@@ -52,21 +122,150 @@ func NewServer(blogService blog.Service, portfolioService portfolio.Service, ser
 			return
 		}
 
+		if !canView(sessions, request, category.Access) {
+			serveLocked(writer, request, category.Name, false)
+			return
+		}
+
 		allCategories, err := portfolioService.GetCategories()
 		if err != nil {
 			http.Error(writer, "Failed to load categories", http.StatusInternalServerError)
 			return
 		}
 
-		var photoToBlog map[string]string
-		posts, err := blogService.GetAllPosts()
-		if err == nil {
-			photoToBlog = blog.BuildPhotoToBlogMap(posts)
-		} else {
-			photoToBlog = make(map[string]string)
+		components.PortfolioCategory(category, allCategories, loadPhotoToBlog(blogService)).Render(request.Context(), writer)
+	}))
+
+	// Unlocks the private album holding the posted page, then shows the page.
+	mux.HandleFunc("POST /portfolio/{path...}", func(writer http.ResponseWriter, request *http.Request) {
+		albumPath := strings.TrimSuffix(request.PathValue("path"), "/")
+		category, err := portfolioService.GetCategory(albumPath)
+		if err == portfolio.ErrCategoryNotFound {
+			if idx := strings.LastIndex(albumPath, "/"); idx > 0 {
+				category, err = portfolioService.GetCategory(albumPath[:idx])
+			}
+		}
+		if err != nil {
+			if err == portfolio.ErrCategoryNotFound {
+				http.NotFound(writer, request)
+				return
+			}
+			http.Error(writer, "Failed to load category", http.StatusInternalServerError)
+			return
+		}
+		if !category.Access.IsPrivate() {
+			http.Redirect(writer, request, request.URL.Path, http.StatusSeeOther)
+			return
+		}
+
+		matched, ok := passwords.check(writer, category.Access.PasswordHash, request.PostFormValue("password"))
+		if !ok {
+			return
+		}
+		if !matched {
+			serveLocked(writer, request, category.Name, true)
+			return
+		}
+		sessions.Grant(writer, request, category.Access.Album, time.Now().Add(access.SessionTTL))
+		http.Redirect(writer, request, request.URL.Path, http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("GET /prints/{path...}", func(writer http.ResponseWriter, request *http.Request) {
+		photo, ok := findPrintablePhoto(writer, request, portfolioService, sessions)
+		if !ok {
+			return
+		}
+		components.PrintOrderPage(photo, orders.Request{}, nil, false).Render(request.Context(), writer)
+	})
+
+	mux.HandleFunc("POST /prints/{path...}", func(writer http.ResponseWriter, request *http.Request) {
+		photo, ok := findPrintablePhoto(writer, request, portfolioService, sessions)
+		if !ok {
+			return
+		}
+
+		request.Body = http.MaxBytesReader(writer, request.Body, 64<<10)
+		quantity, _ := strconv.Atoi(request.PostFormValue("quantity"))
+		form := orders.Request{
+			Photo:    photo.Permalink(),
+			Size:     request.PostFormValue("size"),
+			Quantity: quantity,
+			Name:     strings.TrimSpace(request.PostFormValue("name")),
+			Email:    strings.TrimSpace(request.PostFormValue("email")),
+			Message:  strings.TrimSpace(request.PostFormValue("message")),
+		}
+		if problems := form.Validate(photo.PrintSizes); problems != nil {
+			writer.WriteHeader(http.StatusUnprocessableEntity)
+			components.PrintOrderPage(photo, form, problems, false).Render(request.Context(), writer)
+			return
+		}
+		if _, err := orderStore.Add(form); err != nil {
+			http.Error(writer, "Failed to save print request", http.StatusInternalServerError)
+			return
+		}
+		components.PrintOrderPage(photo, form, nil, true).Render(request.Context(), writer)
+	})
+
+	mux.HandleFunc("GET /admin/orders", func(writer http.ResponseWriter, request *http.Request) {
+		if serverConfig.AdminPasswordHash == "" {
+			http.NotFound(writer, request)
+			return
+		}
+		_, password, ok := request.BasicAuth()
+		matched := false
+		if ok {
+			if matched, ok = passwords.check(writer, serverConfig.AdminPasswordHash, password); !ok {
+				return
+			}
+		}
+		if !matched {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		requests, err := orderStore.List()
+		if err != nil {
+			http.Error(writer, "Failed to load print requests", http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Cache-Control", "no-store")
+		components.OrdersPage(requests).Render(request.Context(), writer)
+	})
+
+	mux.HandleFunc("GET /collections/{name}", func(writer http.ResponseWriter, request *http.Request) {
+		collection, err := portfolioService.GetCollection(request.PathValue("name"))
+		if err != nil {
+			if err == portfolio.ErrCollectionNotFound {
+				http.NotFound(writer, request)
+				return
+			}
+			http.Error(writer, "Failed to load collection", http.StatusInternalServerError)
+			return
+		}
+
+		components.CollectionPage(collection, loadPhotoToBlog(blogService)).Render(request.Context(), writer)
+	})
+
+	mux.HandleFunc("GET /map", func(writer http.ResponseWriter, request *http.Request) {
+		categories, err := portfolioService.GetCategories()
+		if err != nil {
+			http.Error(writer, "Failed to load portfolio categories", http.StatusInternalServerError)
+			return
+		}
+
+		components.MapPage(portfolio.GeotaggedImages(categories), loadPhotoToBlog(blogService)).Render(request.Context(), writer)
+	})
+
+	mux.HandleFunc("GET /api/photos.geojson", func(writer http.ResponseWriter, request *http.Request) {
+		categories, err := portfolioService.GetCategories()
+		if err != nil {
+			http.Error(writer, "Failed to load portfolio categories", http.StatusInternalServerError)
+			return
 		}
 
-		components.PortfolioCategory(category, allCategories, photoToBlog).Render(request.Context(), writer)
+		writer.Header().Set("Content-Type", "application/geo+json")
+		json.NewEncoder(writer).Encode(portfolio.GeoJSON(portfolio.GeotaggedImages(categories)))
 	})
 
 	mux.HandleFunc("GET /blog", func(writer http.ResponseWriter, request *http.Request) {
@@ -99,8 +298,7 @@ func NewServer(blogService blog.Service, portfolioService portfolio.Service, ser
 		components.BlogPost(post, prevPost, nextPost).Render(request.Context(), writer)
 	})
 
-	imageHandler := NewImageHandler(serverConfig.PortfolioAssetsPath)
-	mux.Handle("/assets/portfolio/", http.StripPrefix("/assets/portfolio/", imageHandler))
+	mux.Handle("/assets/portfolio/", withShareLinks(sessions, shareLinks, http.StripPrefix("/assets/portfolio/", imageHandler).ServeHTTP))
 
 	mux.Handle("/assets/aboutme/", http.StripPrefix("/assets/aboutme/", http.FileServer(http.Dir(serverConfig.AboutmeAssetsPath))))
 
@@ -114,3 +312,122 @@ func NewServer(blogService blog.Service, portfolioService portfolio.Service, ser
 
 	return mux
 }
+
+// loadPhotoToBlog maps photo paths to the slugs of the posts that feature them.
+// Stories are optional, so a blog failure yields an empty map.
+func loadPhotoToBlog(blogService blog.Service) map[string]string {
+	posts, err := blogService.GetAllPosts()
+	if err != nil {
+		return make(map[string]string)
+	}
+	return blog.BuildPhotoToBlogMap(posts)
+}
+
+// withShareLinks unlocks the album named by a valid share link for the rest of
+// the link's lifetime, then redirects to the same URL without the link's parameters.
+func withShareLinks(sessions *access.Sessions, shareLinks *access.ShareLinks, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		if !query.Has(access.ShareSignatureParam) {
+			next(writer, request)
+			return
+		}
+
+		if album, expires, ok := shareLinks.Verify(query); ok {
+			sessions.Grant(writer, request, album, expires)
+		}
+		query.Del(access.ShareAlbumParam)
+		query.Del(access.ShareExpiresParam)
+		query.Del(access.ShareSignatureParam)
+		target := *request.URL
+		target.RawQuery = query.Encode()
+		http.Redirect(writer, request, target.RequestURI(), http.StatusSeeOther)
+	})
+}
+
+// canView reports whether the request may see an album with the given access.
+func canView(sessions *access.Sessions, request *http.Request, albumAccess portfolio.Access) bool {
+	return !albumAccess.IsPrivate() || sessions.Allowed(request, albumAccess.Album)
+}
+
+// serveLocked asks for the password of a private album.
+func serveLocked(writer http.ResponseWriter, request *http.Request, name string, failed bool) {
+	writer.WriteHeader(http.StatusUnauthorized)
+	components.LockedPage(name, failed).Render(request.Context(), writer)
+}
+
+// findPrintablePhoto resolves the "album/slug" path of a print request to a
+// photo offered as a print. Otherwise it writes the response and returns false.
+func findPrintablePhoto(writer http.ResponseWriter, request *http.Request, portfolioService portfolio.Service, sessions *access.Sessions) (portfolio.Image, bool) {
+	photoPath := strings.TrimSuffix(request.PathValue("path"), "/")
+	idx := strings.LastIndex(photoPath, "/")
+	if idx <= 0 {
+		http.NotFound(writer, request)
+		return portfolio.Image{}, false
+	}
+
+	album, err := portfolioService.GetCategory(photoPath[:idx])
+	if err != nil {
+		if err == portfolio.ErrCategoryNotFound {
+			http.NotFound(writer, request)
+			return portfolio.Image{}, false
+		}
+		http.Error(writer, "Failed to load photo", http.StatusInternalServerError)
+		return portfolio.Image{}, false
+	}
+	photo, _, _ := portfolio.FindPhoto(album.Images, photoPath[idx+1:])
+	if photo == nil || len(photo.PrintSizes) == 0 || !canView(sessions, request, album.Access) {
+		http.NotFound(writer, request)
+		return portfolio.Image{}, false
+	}
+	return *photo, true
+}
+
+// serveDownload streams an archive of an album that opted in to downloads.
+func serveDownload(writer http.ResponseWriter, request *http.Request, portfolioService portfolio.Service, sessions *access.Sessions, downloads *albumDownloads, albumPath string) {
+	album, err := portfolioService.GetCategory(albumPath)
+	if err != nil {
+		if err == portfolio.ErrCategoryNotFound {
+			http.NotFound(writer, request)
+			return
+		}
+		http.Error(writer, "Failed to load category", http.StatusInternalServerError)
+		return
+	}
+	if !album.Downloadable {
+		http.NotFound(writer, request)
+		return
+	}
+	if !canView(sessions, request, album.Access) {
+		serveLocked(writer, request, album.Name, false)
+		return
+	}
+
+	downloads.serve(writer, request, album)
+}
+
+func servePhoto(writer http.ResponseWriter, request *http.Request, portfolioService portfolio.Service, blogService blog.Service, sessions *access.Sessions, albumPath, slug string) {
+	album, err := portfolioService.GetCategory(albumPath)
+	if err != nil {
+		if err == portfolio.ErrCategoryNotFound {
+			http.NotFound(writer, request)
+			return
+		}
+		http.Error(writer, "Failed to load photo", http.StatusInternalServerError)
+		return
+	}
+
+	if !canView(sessions, request, album.Access) {
+		serveLocked(writer, request, album.Name, false)
+		return
+	}
+
+	photo, prevPhoto, nextPhoto := portfolio.FindPhoto(album.Images, slug)
+	if photo == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	storySlug := loadPhotoToBlog(blogService)[photo.Path]
+	components.PhotoPage(album, *photo, prevPhoto, nextPhoto, storySlug).Render(request.Context(), writer)
+}
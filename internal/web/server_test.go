@@ -1,14 +1,25 @@
 package web
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"image"
+	_ "image/jpeg"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"personalwebsite/internal/access"
 	"personalwebsite/internal/blog"
+	"personalwebsite/internal/images"
 	"personalwebsite/internal/portfolio"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
 )
 
 func testServerConfig(t *testing.T) ServerConfig {
@@ -17,6 +28,7 @@ func testServerConfig(t *testing.T) ServerConfig {
 		PortfolioAssetsPath: t.TempDir(),
 		AboutmeAssetsPath:   t.TempDir(),
 		CSSAssetsPath:       "../../internal/assets",
+		OrdersPath:          filepath.Join(t.TempDir(), "orders.jsonl"),
 	}
 }
 
@@ -24,9 +36,9 @@ type mockPortfolioService struct{}
 
 func (s *mockPortfolioService) GetCategories() ([]portfolio.Category, error) {
 	return []portfolio.Category{
-		{Name: "Landscape", Images: []portfolio.Image{{Path: "/assets/l", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/l", Ext: ".jpg"}},
-		{Name: "Wildlife", Images: []portfolio.Image{{Path: "/assets/w", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/w", Ext: ".jpg"}},
-		{Name: "Portraits", Images: []portfolio.Image{{Path: "/assets/p", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/p", Ext: ".jpg"}},
+		{Name: "Landscape", Path: "Landscape", Images: []portfolio.Image{{Path: "/assets/l", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/l", Ext: ".jpg"}},
+		{Name: "Wildlife", Path: "Wildlife", Images: []portfolio.Image{{Path: "/assets/w", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/w", Ext: ".jpg"}},
+		{Name: "Portraits", Path: "Portraits", Images: []portfolio.Image{{Path: "/assets/p", Ext: ".jpg"}}, CoverImage: portfolio.Image{Path: "/assets/p", Ext: ".jpg"}},
 	}, nil
 }
 
@@ -34,6 +46,7 @@ func (s *mockPortfolioService) GetCategory(name string) (portfolio.Category, err
 	if name == "Landscape" {
 		return portfolio.Category{
 			Name:       "Landscape",
+			Path:       "Landscape",
 			Images:     []portfolio.Image{{Path: "/assets/l", Ext: ".jpg"}},
 			CoverImage: portfolio.Image{Path: "/assets/l", Ext: ".jpg"},
 		}, nil
@@ -41,6 +54,25 @@ func (s *mockPortfolioService) GetCategory(name string) (portfolio.Category, err
 	return portfolio.Category{}, portfolio.ErrCategoryNotFound
 }
 
+func (s *mockPortfolioService) GetCollections() ([]portfolio.Collection, error) {
+	collection, _ := s.GetCollection("best-of-2024")
+	return []portfolio.Collection{collection}, nil
+}
+
+func (s *mockPortfolioService) GetCollection(name string) (portfolio.Collection, error) {
+	if name == "best-of-2024" {
+		return portfolio.Collection{
+			Name:  "best-of-2024",
+			Title: "Best of 2024",
+			Images: []portfolio.Image{
+				{Path: "/assets/portfolio/Landscape/l", Ext: ".jpg", Widths: images.FullLadder.Widths},
+				{Path: "/assets/portfolio/Alaska/a", Ext: ".jpg", Widths: images.FullLadder.Widths},
+			},
+		}, nil
+	}
+	return portfolio.Collection{}, portfolio.ErrCollectionNotFound
+}
+
 func TestServer(t *testing.T) {
 	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, testServerConfig(t))
 
@@ -271,13 +303,972 @@ type mockPortfolioServiceWithPhoto struct{}
 
 func (s *mockPortfolioServiceWithPhoto) GetCategories() ([]portfolio.Category, error) {
 	return []portfolio.Category{
-		{Name: "TestCat", Images: []portfolio.Image{{Path: "/assets/portfolio/TestCat/p1", Ext: ".jpg"}}},
+		{Name: "TestCat", Path: "TestCat", Images: []portfolio.Image{{Path: "/assets/portfolio/TestCat/p1", Ext: ".jpg", Widths: images.FullLadder.Widths}}},
 	}, nil
 }
 
 func (s *mockPortfolioServiceWithPhoto) GetCategory(name string) (portfolio.Category, error) {
 	if name == "TestCat" {
-		return portfolio.Category{Name: "TestCat", Images: []portfolio.Image{{Path: "/assets/portfolio/TestCat/p1", Ext: ".jpg"}}}, nil
+		return portfolio.Category{Name: "TestCat", Path: "TestCat", Images: []portfolio.Image{{Path: "/assets/portfolio/TestCat/p1", Ext: ".jpg", Widths: images.FullLadder.Widths}}}, nil
 	}
 	return portfolio.Category{}, portfolio.ErrCategoryNotFound
 }
+
+type mockNestedPortfolioService struct{}
+
+func (s *mockNestedPortfolioService) GetCategories() ([]portfolio.Category, error) {
+	return []portfolio.Category{
+		{Name: "Alaska", Path: "Alaska", Albums: []portfolio.Category{{Name: "2018", Path: "Alaska/2018"}}},
+	}, nil
+}
+
+func (s *mockNestedPortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Alaska/2018" {
+		return portfolio.Category{
+			Name:    "2018",
+			Path:    "Alaska/2018",
+			Images:  []portfolio.Image{{Path: "/assets/portfolio/Alaska/2018/glacier", Ext: ".jpg", Widths: images.FullLadder.Widths}},
+			Parents: []portfolio.Breadcrumb{{Name: "Alaska", Path: "Alaska"}},
+		}, nil
+	}
+	return portfolio.Category{}, portfolio.ErrCategoryNotFound
+}
+
+func TestPortfolioCategory_NestedAlbum(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockNestedPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/Alaska/2018", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, `href="/portfolio/Alaska"`) {
+		t.Errorf("expected breadcrumb link to parent album; got body: %s", body)
+	}
+	if !strings.Contains(body, "/assets/portfolio/Alaska/2018/glacier_w600.jpg") {
+		t.Errorf("expected nested album image; got body: %s", body)
+	}
+}
+
+func (s *mockPortfolioServiceWithPhoto) GetCollections() ([]portfolio.Collection, error) {
+	return nil, nil
+}
+
+func (s *mockPortfolioServiceWithPhoto) GetCollection(name string) (portfolio.Collection, error) {
+	return portfolio.Collection{}, portfolio.ErrCollectionNotFound
+}
+
+func (s *mockNestedPortfolioService) GetCollections() ([]portfolio.Collection, error) {
+	return nil, nil
+}
+
+func (s *mockNestedPortfolioService) GetCollection(name string) (portfolio.Collection, error) {
+	return portfolio.Collection{}, portfolio.ErrCollectionNotFound
+}
+
+func TestCollection(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/collections/best-of-2024", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	required := []string{"Best of 2024", "/assets/portfolio/Landscape/l_w600.jpg", "/assets/portfolio/Alaska/a_w600.jpg", `x-data="gallery"`}
+	for _, expected := range required {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected body to contain '%s'; got body: %s", expected, body)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/collections/missing", nil)
+	recorder = httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status NotFound; got %v", recorder.Code)
+	}
+}
+
+type mockTaggedPortfolioService struct {
+	mockPortfolioService
+}
+
+func (s *mockTaggedPortfolioService) GetCategories() ([]portfolio.Category, error) {
+	category, _ := s.GetCategory("Wildlife")
+	return []portfolio.Category{category}, nil
+}
+
+func (s *mockTaggedPortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Wildlife" {
+		return portfolio.Category{
+			Name: "Wildlife",
+			Path: "Wildlife",
+			Images: []portfolio.Image{
+				{Path: "/assets/portfolio/Wildlife/bear", Ext: ".jpg", Widths: images.FullLadder.Widths, Keywords: []string{"bear", "river"}},
+				{Path: "/assets/portfolio/Wildlife/eagle", Ext: ".jpg", Widths: images.FullLadder.Widths, Keywords: []string{"bird"}},
+			},
+		}, nil
+	}
+	return portfolio.Category{}, portfolio.ErrCategoryNotFound
+}
+
+func TestPortfolioTag(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockTaggedPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/tags/Bear", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "/assets/portfolio/Wildlife/bear_w600.jpg") {
+		t.Errorf("expected tagged image in body; got body: %s", body)
+	}
+	if strings.Contains(body, "/assets/portfolio/Wildlife/eagle_w600.jpg") {
+		t.Errorf("expected untagged image to be excluded; got body: %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/portfolio/tags/unknown", nil)
+	recorder = httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status NotFound; got %v", recorder.Code)
+	}
+}
+
+func TestPortfolioCategory_KeywordFilterChips(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockTaggedPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/Wildlife", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, keyword := range []string{"bear", "bird", "river"} {
+		if !strings.Contains(body, "activeTag = &#34;"+keyword+"&#34;") {
+			t.Errorf("expected filter chip for '%s'; got body: %s", keyword, body)
+		}
+	}
+}
+
+func TestPortfolioPhotoPage(t *testing.T) {
+	srv := NewServer(&mockLinkedPhotosService{}, &mockPortfolioServiceWithPhoto{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/TestCat/p1", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	required := []string{"/assets/portfolio/TestCat/p1_w1600.jpg", `href="/blog/photo-post"`, `href="/portfolio/TestCat"`}
+	for _, expected := range required {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected body to contain '%s'; got body: %s", expected, body)
+		}
+	}
+
+	for _, path := range []string{"/portfolio/TestCat/missing", "/portfolio/Missing/p1"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		recorder = httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status NotFound; got %v", path, recorder.Code)
+		}
+	}
+}
+
+type mockGeotaggedPortfolioService struct {
+	mockPortfolioService
+}
+
+func (s *mockGeotaggedPortfolioService) GetCategories() ([]portfolio.Category, error) {
+	return []portfolio.Category{{
+		Name: "Alaska",
+		Path: "Alaska",
+		Images: []portfolio.Image{
+			{Path: "/assets/portfolio/Alaska/denali", Ext: ".jpg", Album: "Alaska", Location: &images.GeoPoint{Latitude: 63.1, Longitude: -151}},
+			{Path: "/assets/portfolio/Alaska/untagged", Ext: ".jpg", Album: "Alaska"},
+		},
+	}}, nil
+}
+
+func TestPhotosGeoJSON(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockGeotaggedPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/photos.geojson", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/geo+json" {
+		t.Errorf("expected application/geo+json; got %q", contentType)
+	}
+
+	var collection portfolio.FeatureCollection
+	if err := json.Unmarshal(recorder.Body.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	if len(collection.Features) != 1 {
+		t.Fatalf("expected 1 feature; got %d", len(collection.Features))
+	}
+	if collection.Features[0].Properties.Permalink != "/portfolio/Alaska/denali" {
+		t.Errorf("unexpected feature %+v", collection.Features[0])
+	}
+}
+
+func TestMapPage(t *testing.T) {
+	srv := NewServer(blog.NewMemoryService(), &mockGeotaggedPortfolioService{}, testServerConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/map", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, expected := range []string{`id="photo-map"`, "/api/photos.geojson", "leaflet.markercluster", "denali"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in body", expected)
+		}
+	}
+	if strings.Contains(body, "untagged") {
+		t.Errorf("expected photos without a location to be left off the map")
+	}
+}
+
+// writeGeotaggedJPEG copies internal/testdata/geotagged.jpg, a small JPEG
+// whose EXIF places it at 61°13'12"N 149°53'24"W (61.22, -149.89), to path.
+func writeGeotaggedJPEG(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "geotagged.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedLocation(t *testing.T, srv http.Handler, url string) *images.GeoPoint {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("%s: expected status OK; got %v", url, recorder.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), "served.jpg")
+	if err := os.WriteFile(path, recorder.Body.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := images.ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("%s: %v", url, err)
+	}
+	return meta.Location
+}
+
+func TestPortfolioAssets_AppliesMetadataPolicy(t *testing.T) {
+	cfg := testServerConfig(t)
+	publicDir := filepath.Join(cfg.PortfolioAssetsPath, "Alaska")
+	privateDir := filepath.Join(publicDir, "Home")
+	os.MkdirAll(privateDir, 0755)
+	os.WriteFile(filepath.Join(privateDir, "album.yaml"), []byte("location: hidden\n"), 0644)
+	writeGeotaggedJPEG(t, filepath.Join(publicDir, "denali.jpg"))
+	writeGeotaggedJPEG(t, filepath.Join(publicDir, "denali_w600.jpg"))
+	writeGeotaggedJPEG(t, filepath.Join(privateDir, "cabin.jpg"))
+	os.WriteFile(filepath.Join(publicDir, "denali.xmp"), []byte("<x:xmpmeta/>"), 0644)
+	os.WriteFile(filepath.Join(cfg.PortfolioAssetsPath, ".manifest.json"), []byte(`{"files":{"Alaska/Home/cabin.jpg":{}}}`), 0644)
+
+	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, cfg)
+
+	if location := servedLocation(t, srv, "/assets/portfolio/Alaska/denali.jpg"); location == nil || location.Latitude != 61.2 || location.Longitude != -149.9 {
+		t.Errorf("expected the original to keep a coarse location by default; got %v", location)
+	}
+	if location := servedLocation(t, srv, "/assets/portfolio/Alaska/denali_w600.jpg"); location != nil {
+		t.Errorf("expected variants to carry no GPS; got %v", *location)
+	}
+	if location := servedLocation(t, srv, "/assets/portfolio/Alaska/Home/cabin.jpg"); location != nil {
+		t.Errorf("expected a hidden album to carry no GPS; got %v", *location)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/portfolio/Alaska/denali.xmp", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected sidecars not to be served; got %v", recorder.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/portfolio/.manifest.json", nil)
+	recorder = httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected the optimizer's manifest not to be served; got %v", recorder.Code)
+	}
+}
+
+type mockPrivatePortfolioService struct {
+	mockPortfolioService
+	passwordHash string
+}
+
+func (s *mockPrivatePortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Clients" {
+		return portfolio.Category{
+			Name:   "Clients",
+			Path:   "Clients",
+			Images: []portfolio.Image{{Path: "/assets/portfolio/Clients/wedding", Ext: ".jpg", Widths: images.FullLadder.Widths, Album: "Clients"}},
+			Access: portfolio.Access{Album: "Clients", PasswordHash: s.passwordHash},
+		}, nil
+	}
+	return s.mockPortfolioService.GetCategory(name)
+}
+
+func TestPrivateGallery_RequiresPassword(t *testing.T) {
+	passwordHash, err := access.HashPassword("proofs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testServerConfig(t)
+	clientsDir := filepath.Join(cfg.PortfolioAssetsPath, "Clients")
+	os.MkdirAll(clientsDir, 0755)
+	os.WriteFile(filepath.Join(clientsDir, "album.yaml"), []byte("private: true\npassword_hash: "+passwordHash+"\n"), 0644)
+	os.WriteFile(filepath.Join(clientsDir, "notes.txt"), []byte("client notes"), 0644)
+	srv := NewServer(blog.NewMemoryService(), &mockPrivatePortfolioService{passwordHash: passwordHash}, cfg)
+
+	get := func(url string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+	unlock := func(url, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for _, url := range []string{"/portfolio/Clients", "/portfolio/Clients/wedding"} {
+		recorder := get(url, nil)
+		if recorder.Code != http.StatusUnauthorized || strings.Contains(recorder.Body.String(), "wedding_w") {
+			t.Errorf("%s: expected a locked page; got %v", url, recorder.Code)
+		}
+	}
+	if recorder := get("/assets/portfolio/Clients/notes.txt", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected private assets to be hidden; got %v", recorder.Code)
+	}
+
+	if recorder := unlock("/portfolio/Clients", "wrong"); recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), "not correct") {
+		t.Errorf("expected a wrong password to be rejected; got %v", recorder.Code)
+	}
+
+	recorder := unlock("/portfolio/Clients/wedding", "proofs")
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/portfolio/Clients/wedding" {
+		t.Fatalf("expected a redirect back to the photo; got %v %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+	cookies := recorder.Result().Cookies()
+
+	if recorder := get("/portfolio/Clients", cookies); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "wedding_w600.jpg") {
+		t.Errorf("expected the unlocked gallery; got %v", recorder.Code)
+	}
+	if recorder := get("/assets/portfolio/Clients/notes.txt", cookies); recorder.Code != http.StatusOK {
+		t.Errorf("expected unlocked assets to be served; got %v", recorder.Code)
+	} else if recorder.Header().Get("Cache-Control") != "private, no-cache" || recorder.Header().Get("Vary") != "Cookie" {
+		t.Errorf("expected private assets to stay out of shared caches; got %q, Vary %q", recorder.Header().Get("Cache-Control"), recorder.Header().Get("Vary"))
+	}
+	if recorder := get("/assets/portfolio/Clients/album.yaml", cookies); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected album settings never to be served; got %v", recorder.Code)
+	}
+}
+
+func TestShareLink_UnlocksPrivateGallery(t *testing.T) {
+	cfg := testServerConfig(t)
+	cfg.SessionSecret = []byte("test-secret")
+	clientsDir := filepath.Join(cfg.PortfolioAssetsPath, "Clients")
+	os.MkdirAll(clientsDir, 0755)
+	os.WriteFile(filepath.Join(clientsDir, "album.yaml"), []byte("private: true\n"), 0644)
+	os.WriteFile(filepath.Join(clientsDir, "notes.txt"), []byte("client notes"), 0644)
+	srv := NewServer(blog.NewMemoryService(), &mockPrivatePortfolioService{}, cfg)
+
+	links := access.NewShareLinks(cfg.SessionSecret)
+	serve := func(url string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	expired := serve("/portfolio/Clients?"+links.Sign("Clients", time.Now().Add(-time.Minute)).Encode(), nil)
+	if len(expired.Result().Cookies()) != 0 {
+		t.Error("expected an expired link not to unlock anything")
+	}
+
+	recorder := serve("/portfolio/Clients?"+links.Sign("Clients", time.Now().Add(time.Hour)).Encode(), nil)
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/portfolio/Clients" {
+		t.Fatalf("expected a redirect to the clean URL; got %v %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+	cookies := recorder.Result().Cookies()
+
+	if recorder := serve("/portfolio/Clients", cookies); recorder.Code != http.StatusOK {
+		t.Errorf("expected the shared gallery; got %v", recorder.Code)
+	}
+	if recorder := serve("/assets/portfolio/Clients/notes.txt", cookies); recorder.Code != http.StatusOK {
+		t.Errorf("expected shared assets to be served; got %v", recorder.Code)
+	}
+
+	forged := links.Sign("Clients", time.Now().Add(time.Hour))
+	forged.Set(access.ShareAlbumParam, "Other")
+	if recorder := serve("/assets/portfolio/Clients/notes.txt?"+forged.Encode(), nil); len(recorder.Result().Cookies()) != 0 {
+		t.Error("expected a forged link not to unlock anything")
+	}
+}
+
+type mockDownloadablePortfolioService struct {
+	mockPortfolioService
+}
+
+func (s *mockDownloadablePortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Trips" {
+		return portfolio.Category{
+			Name:         "Trips",
+			Path:         "Trips",
+			Images:       []portfolio.Image{{Path: "/assets/portfolio/Trips/denali", Ext: ".jpg", Album: "Trips"}},
+			Downloadable: true,
+			Albums: []portfolio.Category{
+				{
+					Name:         "Day 1",
+					Path:         "Trips/Day 1",
+					Images:       []portfolio.Image{{Path: "/assets/portfolio/Trips/Day 1/camp", Ext: ".jpg", Album: "Trips/Day 1"}},
+					Downloadable: true,
+				},
+				{
+					Name:   "Outtakes",
+					Path:   "Trips/Outtakes",
+					Images: []portfolio.Image{{Path: "/assets/portfolio/Trips/Outtakes/blur", Ext: ".jpg", Album: "Trips/Outtakes"}},
+				},
+			},
+		}, nil
+	}
+	return s.mockPortfolioService.GetCategory(name)
+}
+
+func TestAlbumDownload(t *testing.T) {
+	cfg := testServerConfig(t)
+	tripsDir := filepath.Join(cfg.PortfolioAssetsPath, "Trips")
+	os.MkdirAll(filepath.Join(tripsDir, "Day 1"), 0755)
+	os.WriteFile(filepath.Join(tripsDir, "album.yaml"), []byte("downloadable: true\nlocation: hidden\n"), 0644)
+	writeGeotaggedJPEG(t, filepath.Join(tripsDir, "denali.jpg"))
+	camp, err := os.Create(filepath.Join(tripsDir, "Day 1", "camp.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.WriteJPEG(camp, image.NewRGBA(image.Rect(0, 0, 1200, 800)), 85, nil); err != nil {
+		t.Fatal(err)
+	}
+	camp.Close()
+	srv := NewServer(blog.NewMemoryService(), &mockDownloadablePortfolioService{}, cfg)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+	readArchive := func(recorder *httptest.ResponseRecorder) map[string][]byte {
+		t.Helper()
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("expected a ZIP archive; got %v %q", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string][]byte{}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[file.Name] = data
+		}
+		return files
+	}
+
+	if recorder := get("/portfolio/Trips"); !strings.Contains(recorder.Body.String(), "/portfolio/Trips/download") {
+		t.Error("expected the album page to link to its archive")
+	}
+
+	recorder := get("/portfolio/Trips/download")
+	if disposition := recorder.Header().Get("Content-Disposition"); disposition != "attachment; filename=Trips.zip" {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+	files := readArchive(recorder)
+	if len(files) != 2 || files["denali.jpg"] == nil || files["Day 1/camp.jpg"] == nil {
+		t.Fatalf("expected the album and its downloadable nested album; got %d files", len(files))
+	}
+	path := filepath.Join(t.TempDir(), "denali.jpg")
+	os.WriteFile(path, files["denali.jpg"], 0644)
+	if meta, err := images.ReadMetadata(path); err != nil || meta.Location != nil {
+		t.Errorf("expected archived originals to follow the metadata policy; got %v", err)
+	}
+
+	resized := readArchive(get("/portfolio/Trips/download?w=600"))
+	if img, _, err := image.Decode(bytes.NewReader(resized["Day 1/camp.jpg"])); err != nil || img.Bounds().Dx() != 600 {
+		t.Errorf("expected 600px wide photos; got %v", err)
+	}
+
+	if recorder := get("/portfolio/Trips/download?w=123"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected unsupported widths to be rejected; got %v", recorder.Code)
+	}
+	if recorder := get("/portfolio/Landscape/download"); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected albums that did not opt in to have no archive; got %v", recorder.Code)
+	}
+}
+
+func TestAlbumDownload_LimitsConcurrency(t *testing.T) {
+	downloads := newAlbumDownloads(NewImageHandler(t.TempDir(), access.NewSessions(nil)), 1)
+	downloads.slots <- struct{}{}
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolio/Trips/download", nil)
+	recorder := httptest.NewRecorder()
+	downloads.serve(recorder, req, portfolio.Category{Name: "Trips", Downloadable: true})
+
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("expected busy downloads to be turned away; got %v", recorder.Code)
+	}
+}
+
+func TestPasswordChecks_LimitsConcurrency(t *testing.T) {
+	hash, err := access.HashPassword("proofs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwords := newPasswordChecks(1)
+
+	recorder := httptest.NewRecorder()
+	if matched, ok := passwords.check(recorder, hash, "proofs"); !matched || !ok {
+		t.Errorf("expected a free slot to check the password; got matched %v, ok %v", matched, ok)
+	}
+
+	passwords.slots <- struct{}{}
+	recorder = httptest.NewRecorder()
+	if _, ok := passwords.check(recorder, hash, "proofs"); ok {
+		t.Error("expected a check to be refused while the slots are taken")
+	}
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("expected busy password checks to be turned away; got %v", recorder.Code)
+	}
+
+	<-passwords.slots
+	if matched, ok := passwords.check(httptest.NewRecorder(), hash, "wrong"); matched || !ok {
+		t.Errorf("expected a freed slot to check again; got matched %v, ok %v", matched, ok)
+	}
+}
+
+type mockPrintsPortfolioService struct {
+	mockPortfolioService
+}
+
+func (s *mockPrintsPortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Alaska" {
+		return portfolio.Category{
+			Name: "Alaska",
+			Path: "Alaska",
+			Images: []portfolio.Image{
+				{Path: "/assets/portfolio/Alaska/denali", Ext: ".jpg", Album: "Alaska", PrintSizes: []string{"8x10", "12x18"}},
+				{Path: "/assets/portfolio/Alaska/river", Ext: ".jpg", Album: "Alaska"},
+			},
+		}, nil
+	}
+	return s.mockPortfolioService.GetCategory(name)
+}
+
+func TestPrintOrder(t *testing.T) {
+	adminHash, err := access.HashPassword("admin-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testServerConfig(t)
+	cfg.AdminPasswordHash = adminHash
+	srv := NewServer(blog.NewMemoryService(), &mockPrintsPortfolioService{}, cfg)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+	post := func(form string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/prints/Alaska/denali", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(req)
+	}
+
+	if recorder := serve(httptest.NewRequest(http.MethodGet, "/portfolio/Alaska/denali", nil)); !strings.Contains(recorder.Body.String(), "/prints/Alaska/denali") {
+		t.Error("expected the photo page to offer prints")
+	}
+	recorder := serve(httptest.NewRequest(http.MethodGet, "/prints/Alaska/denali", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "12x18") {
+		t.Errorf("expected the order form with the photo's sizes; got %v", recorder.Code)
+	}
+	if recorder := serve(httptest.NewRequest(http.MethodGet, "/prints/Alaska/river", nil)); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected photos without print sizes to have no order form; got %v", recorder.Code)
+	}
+
+	recorder = post("size=40x60&quantity=1&name=Ada&email=not-an-email")
+	if recorder.Code != http.StatusUnprocessableEntity || !strings.Contains(recorder.Body.String(), "valid email") || !strings.Contains(recorder.Body.String(), `value="Ada"`) {
+		t.Errorf("expected the form back with its problems and input; got %v", recorder.Code)
+	}
+
+	recorder = post("size=12x18&quantity=2&name=Ada&email=ada@example.com&message=Framed+please")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "Thank you") {
+		t.Fatalf("expected the request to be accepted; got %v", recorder.Code)
+	}
+
+	if recorder := serve(httptest.NewRequest(http.MethodGet, "/admin/orders", nil)); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected the admin page to require a password; got %v", recorder.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/orders", nil)
+	req.SetBasicAuth("admin", "admin-secret")
+	recorder = serve(req)
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK || !strings.Contains(body, "/portfolio/Alaska/denali") || !strings.Contains(body, "ada@example.com") || !strings.Contains(body, "Framed please") {
+		t.Errorf("expected the admin page to list the request; got %v", recorder.Code)
+	}
+
+	cfg.AdminPasswordHash = ""
+	srv = NewServer(blog.NewMemoryService(), &mockPrintsPortfolioService{}, cfg)
+	if recorder := serve(req); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected no admin page without an admin password; got %v", recorder.Code)
+	}
+}
+
+func TestPortfolioAssets_Crops(t *testing.T) {
+	cfg := testServerConfig(t)
+	os.MkdirAll(filepath.Join(cfg.PortfolioAssetsPath, "Alaska"), 0755)
+	img := image.NewRGBA(image.Rect(0, 0, 1200, 1200))
+	file, err := os.Create(filepath.Join(cfg.PortfolioAssetsPath, "Alaska", "denali.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.WriteJPEG(file, img, 85, nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, cfg)
+
+	tests := []struct {
+		url           string
+		width, height int
+	}{
+		{"/assets/portfolio/Alaska/denali.jpg?w=600&ar=3:2", 600, 400},
+		{"/assets/portfolio/Alaska/denali_w600_3x2.jpg", 600, 400},
+		{"/assets/portfolio/Alaska/denali_w1200.jpg", 1200, 1200},
+		{"/assets/portfolio/Alaska/denali.jpg?w=600&ar=7:5", 1200, 1200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: expected status OK; got %v", tt.url, recorder.Code)
+			continue
+		}
+		config, _, err := image.DecodeConfig(recorder.Body)
+		if err != nil || config.Width != tt.width || config.Height != tt.height {
+			t.Errorf("%s: expected %dx%d; got %dx%d (%v)", tt.url, tt.width, tt.height, config.Width, config.Height, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/portfolio/Alaska/denali_w700_3x2.jpg", nil)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected crops at unlisted widths not to be made; got %v", recorder.Code)
+	}
+}
+
+type mockModernFormatsPortfolioService struct {
+	mockPortfolioService
+}
+
+func (s *mockModernFormatsPortfolioService) GetCategory(name string) (portfolio.Category, error) {
+	if name == "Alaska" {
+		return portfolio.Category{
+			Name:   "Alaska",
+			Path:   "Alaska",
+			Images: []portfolio.Image{{Path: "/assets/portfolio/Alaska/denali", Ext: ".jpg", Widths: images.FullLadder.Widths, Album: "Alaska", Formats: []string{"webp"}}},
+		}, nil
+	}
+	return s.mockPortfolioService.GetCategory(name)
+}
+
+func TestPortfolioAssets_ModernFormats(t *testing.T) {
+	cfg := testServerConfig(t)
+	os.MkdirAll(filepath.Join(cfg.PortfolioAssetsPath, "Alaska"), 0755)
+	file, err := os.Create(filepath.Join(cfg.PortfolioAssetsPath, "Alaska", "denali_w600.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.WriteJPEG(file, image.NewRGBA(image.Rect(0, 0, 600, 400)), 85, nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := os.WriteFile(filepath.Join(cfg.PortfolioAssetsPath, "Alaska", "denali_w600.webp"), []byte("RIFF-webp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(blog.NewMemoryService(), &mockModernFormatsPortfolioService{}, cfg)
+
+	get := func(url, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := get("/assets/portfolio/Alaska/denali_w600.jpg", "image/avif,image/webp,*/*")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/webp" || recorder.Body.String() != "RIFF-webp" {
+		t.Errorf("expected the WebP copy for browsers accepting it; got %v %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if vary := recorder.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected responses to vary by Accept; got %q", vary)
+	}
+
+	recorder = get("/assets/portfolio/Alaska/denali_w600.jpg", "image/*,*/*")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("expected the JPEG for other browsers; got %v %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+
+	body := get("/portfolio/Alaska", "").Body.String()
+	if !strings.Contains(body, `<source type="image/webp" srcset="/assets/portfolio/Alaska/denali_w600.webp 600w, /assets/portfolio/Alaska/denali_w1200.webp 1200w, /assets/portfolio/Alaska/denali_w1600.webp 1600w"`) {
+		t.Error("expected the grid to offer the WebP variants")
+	}
+}
+
+func TestPortfolioAssets_CachingHeaders(t *testing.T) {
+	cfg := testServerConfig(t)
+	os.MkdirAll(filepath.Join(cfg.PortfolioAssetsPath, "Alaska"), 0755)
+	file, err := os.Create(filepath.Join(cfg.PortfolioAssetsPath, "Alaska", "denali.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.WriteJPEG(file, image.NewRGBA(image.Rect(0, 0, 1200, 800)), 85, nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, cfg)
+
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"original", "/assets/portfolio/Alaska/denali.jpg"},
+		{"resized", "/assets/portfolio/Alaska/denali.jpg?w=600"},
+		{"cropped on demand", "/assets/portfolio/Alaska/denali_w600_3x2.jpg"},
+		{"fallback for unlisted widths", "/assets/portfolio/Alaska/denali.jpg?w=700"},
+	}
+	etags := map[string]string{}
+	for _, tt := range tests {
+		recorder := get(tt.url, "")
+		etag := recorder.Header().Get("ETag")
+		if recorder.Code != http.StatusOK || !strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/`) {
+			t.Errorf("%s: expected a strong ETag; got %v %q", tt.name, recorder.Code, etag)
+			continue
+		}
+		etags[tt.name] = etag
+		if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "public, no-cache" {
+			t.Errorf("%s: expected requests to revalidate; got %q", tt.name, cacheControl)
+		}
+
+		recorder = get(tt.url, etag)
+		if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 for a matching If-None-Match; got %v", tt.name, recorder.Code)
+		}
+		if recorder = get(tt.url, `"stale"`); recorder.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for a stale ETag; got %v", tt.name, recorder.Code)
+		}
+	}
+
+	if etags["original"] == etags["resized"] {
+		t.Error("expected resized variants to have their own ETags")
+	}
+	if etags["original"] != etags["fallback for unlisted widths"] {
+		t.Error("expected the fallback to carry the original's ETag")
+	}
+
+	// Pages link prebuilt variants by the fingerprint of their published file.
+	variant := filepath.Join(cfg.PortfolioAssetsPath, "Alaska", "denali_w600.jpg")
+	if err := os.WriteFile(variant, []byte(get("/assets/portfolio/Alaska/denali.jpg?w=600", "").Body.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := images.Fingerprint(variant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		url          string
+		cacheControl string
+	}{
+		{"/assets/portfolio/Alaska/denali_w600.jpg?v=" + hash, "public, max-age=31536000, immutable"},
+		{"/assets/portfolio/Alaska/denali_w600.jpg?v=0000000000000000", "public, no-cache"},
+		{"/assets/portfolio/Alaska/denali.jpg?w=600&v=" + hash, "public, no-cache"},
+		{"/assets/portfolio/Alaska/denali_w1200.jpg?v=" + hash, "public, no-cache"},
+	} {
+		recorder := get(tt.url, "")
+		if cacheControl := recorder.Header().Get("Cache-Control"); recorder.Code != http.StatusOK || cacheControl != tt.cacheControl {
+			t.Errorf("%s: expected %q; got %v %q", tt.url, tt.cacheControl, recorder.Code, cacheControl)
+		}
+	}
+}
+
+func TestSlideshow(t *testing.T) {
+	cfg := testServerConfig(t)
+	srv := NewServer(blog.NewMemoryService(), &mockPrivatePortfolioService{}, cfg)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := get("/portfolio/Landscape/slideshow")
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK || !strings.Contains(body, `"Path":"/assets/l"`) || !strings.Contains(body, "interval: 8000, shuffle: false, captions: true") {
+		t.Errorf("expected the album's slideshow with default settings; got %v", recorder.Code)
+	}
+
+	body = get("/portfolio/Landscape/slideshow?interval=15s&shuffle=true&captions=false").Body.String()
+	if !strings.Contains(body, "interval: 15000, shuffle: true, captions: false") {
+		t.Error("expected the query to override the settings")
+	}
+	if body := get("/portfolio/Landscape/slideshow?interval=10ms").Body.String(); !strings.Contains(body, "interval: 2000,") {
+		t.Error("expected too short an interval to be raised")
+	}
+
+	body = get("/portfolio/slideshow").Body.String()
+	for _, path := range []string{"/assets/l", "/assets/w", "/assets/p"} {
+		if !strings.Contains(body, `"Path":"`+path+`"`) {
+			t.Errorf("expected the playlist to include %s", path)
+		}
+	}
+
+	if recorder := get("/portfolio/Clients/slideshow"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected private slideshows to be locked; got %v", recorder.Code)
+	}
+	if recorder := get("/portfolio/Missing/slideshow"); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected missing albums to have no slideshow; got %v", recorder.Code)
+	}
+
+	cfg.Slideshow = SlideshowConfig{Interval: 20 * time.Second, Shuffle: true}
+	srv = NewServer(blog.NewMemoryService(), &mockPrivatePortfolioService{}, cfg)
+	if body := get("/portfolio/Landscape/slideshow").Body.String(); !strings.Contains(body, "interval: 20000, shuffle: true, captions: false") {
+		t.Error("expected the server's slideshow settings")
+	}
+}
+
+func TestPortfolioAssets_WatermarkedAlbum(t *testing.T) {
+	t.Setenv("CACHE_DIR", t.TempDir())
+	cfg := testServerConfig(t)
+	// The README's layout: the mark in .branding, referenced from the album.
+	writeImage := func(relPath string, shade uint8) {
+		path := filepath.Join(cfg.PortfolioAssetsPath, filepath.FromSlash(relPath))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+		for idx := range img.Pix {
+			img.Pix[idx] = shade
+		}
+		if err := imaging.Save(img, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeImage("Wildlife/bear.jpg", 100)
+	writeImage(".branding/mark.png", 255)
+	os.WriteFile(filepath.Join(cfg.PortfolioAssetsPath, "Wildlife", "album.yaml"), []byte("watermark:\n  image: ../.branding/mark.png\n  opacity: 1\n"), 0644)
+	srv := NewServer(blog.NewMemoryService(), &mockPortfolioService{}, cfg)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		return recorder
+	}
+
+	// The full-size photo and every large variant carry the mark in the corner.
+	for _, url := range []string{"/assets/portfolio/Wildlife/bear.jpg", "/assets/portfolio/Wildlife/bear.jpg?w=1600", "/assets/portfolio/Wildlife/bear_w1600.jpg"} {
+		recorder := get(url)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", url, recorder.Code)
+			continue
+		}
+		img, err := imaging.Decode(recorder.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		bounds := img.Bounds()
+		if red, _, _, _ := img.At(bounds.Max.X-8, bounds.Max.Y-8).RGBA(); red>>8 < 200 {
+			t.Errorf("%s: expected the watermark, got corner red %d", url, red>>8)
+		}
+	}
+	if recorder := get("/assets/portfolio/.branding/mark.png"); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected the mark itself not to be served, got %d", recorder.Code)
+	}
+
+	// Without its mark the album fails closed instead of serving clean images.
+	os.Remove(filepath.Join(cfg.PortfolioAssetsPath, ".branding", "mark.png"))
+	for _, url := range []string{"/assets/portfolio/Wildlife/bear.jpg", "/assets/portfolio/Wildlife/bear.jpg?w=1600", "/assets/portfolio/Wildlife/bear_w1600.jpg"} {
+		if recorder := get(url); recorder.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected 500 without the mark, got %d", url, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"net/http"
+	"personalwebsite/internal/access"
+	"personalwebsite/internal/portfolio"
+	"personalwebsite/internal/web/components"
+	"strconv"
+	"time"
+)
+
+// minSlideshowInterval keeps the next photo's preload ahead of the slideshow.
+const minSlideshowInterval = 2 * time.Second
+
+// SlideshowConfig sets how slideshows play. Each can be overridden per screen
+// with the "interval" (e.g. "15s"), "shuffle" and "captions" query parameters.
+type SlideshowConfig struct {
+	Interval time.Duration
+	Shuffle  bool
+	Captions bool
+}
+
+// DefaultSlideshowConfig is used when ServerConfig leaves Slideshow unset.
+var DefaultSlideshowConfig = SlideshowConfig{Interval: 8 * time.Second, Captions: true}
+
+// withQuery applies the overrides in a slideshow URL's query, ignoring invalid ones.
+func (cfg SlideshowConfig) withQuery(request *http.Request) SlideshowConfig {
+	query := request.URL.Query()
+	if interval, err := time.ParseDuration(query.Get("interval")); err == nil {
+		cfg.Interval = interval
+	}
+	if shuffle, err := strconv.ParseBool(query.Get("shuffle")); err == nil {
+		cfg.Shuffle = shuffle
+	}
+	if captions, err := strconv.ParseBool(query.Get("captions")); err == nil {
+		cfg.Captions = captions
+	}
+	cfg.Interval = max(cfg.Interval, minSlideshowInterval)
+	return cfg
+}
+
+// serveAlbumSlideshow plays an album and its nested albums.
+func serveAlbumSlideshow(writer http.ResponseWriter, request *http.Request, portfolioService portfolio.Service, sessions *access.Sessions, cfg SlideshowConfig, albumPath string) {
+	album, err := portfolioService.GetCategory(albumPath)
+	if err != nil {
+		if err == portfolio.ErrCategoryNotFound {
+			http.NotFound(writer, request)
+			return
+		}
+		http.Error(writer, "Failed to load category", http.StatusInternalServerError)
+		return
+	}
+	if !canView(sessions, request, album.Access) {
+		serveLocked(writer, request, album.Name, false)
+		return
+	}
+
+	serveSlideshow(writer, request, cfg, album.Name, portfolio.Playlist([]portfolio.Category{album}))
+}
+
+// serveSlideshow plays images full screen with the configured settings.
+func serveSlideshow(writer http.ResponseWriter, request *http.Request, cfg SlideshowConfig, title string, images []portfolio.Image) {
+	cfg = cfg.withQuery(request)
+	components.SlideshowPage(title, images, cfg.Interval.Milliseconds(), cfg.Shuffle, cfg.Captions).Render(request.Context(), writer)
+}